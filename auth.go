@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// AuthConfig gives yt-dlp credentials, for members-only, private and
+// age-restricted videos.
+type AuthConfig struct {
+	Cookies            string `json:"cookies,omitempty"`              // a cookies.txt file, in Netscape format
+	CookiesFromBrowser string `json:"cookies_from_browser,omitempty"` // e.g. "firefox" or "chrome:Profile 1"
+	Netrc              bool   `json:"netrc,omitempty"`                // log in with ~/.netrc's "machine youtube"
+	NetrcLocation      string `json:"netrc_location,omitempty"`       // a .netrc somewhere else
+}
+
+// ytdlpArgs returns the yt-dlp flags for these credentials.
+func (a AuthConfig) ytdlpArgs() []string {
+	var args []string
+	if a.Cookies != "" {
+		args = append(args, "--cookies", expandPath(a.Cookies))
+	}
+	if a.CookiesFromBrowser != "" {
+		args = append(args, "--cookies-from-browser", a.CookiesFromBrowser)
+	}
+	if a.Netrc || a.NetrcLocation != "" {
+		args = append(args, "--netrc")
+	}
+	if a.NetrcLocation != "" {
+		args = append(args, "--netrc-location", expandPath(a.NetrcLocation))
+	}
+	return args
+}
+
+// expandPath expands $VARS and a leading ~ in a path from the config.
+func expandPath(path string) string {
+	path = os.ExpandEnv(path)
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, rest)
+		}
+	}
+	return path
+}
+
+// addAuthFlags registers flags that override the configured credentials.
+func addAuthFlags(fs *flag.FlagSet) {
+	fs.Func("cookies", "Use the cookies in this cookies.txt `file`, for members-only, private and age-restricted videos", func(s string) error {
+		if _, err := os.Stat(expandPath(s)); err != nil {
+			return errors.New("no such file")
+		}
+		cfg.Auth.Cookies = s
+		return nil
+	})
+	fs.StringVar(&cfg.Auth.CookiesFromBrowser, "cookies-from-browser", cfg.Auth.CookiesFromBrowser, "Use the cookies from this `browser`, e.g. firefox or chrome")
+	fs.BoolVar(&cfg.Auth.Netrc, "netrc", cfg.Auth.Netrc, "Log in with the youtube machine in ~/.netrc")
+}
+
+// authRequired matches yt-dlp's complaints about a video it can't get
+// without signing in.
+var authRequired = regexp.MustCompile(`(?i)sign in to confirm|members-only|join this channel|private video|` +
+	`video is private|login required|requires authentication|--cookies|account cookies|inappropriate for some users`)
+
+// An authError means a video needs credentials that tuber either wasn't
+// given or that didn't work.
+type authError struct {
+	ytdlp *ytdlpError
+}
+
+func (e *authError) Error() string {
+	hint := "This video needs you to be signed in: pass -cookies <cookies.txt> or -cookies-from-browser <browser>, or set auth.cookies in the config"
+	if len(cfg.Auth.ytdlpArgs()) > 0 {
+		hint = "Signing in didn't help: the cookies may have expired, or that account can't see this video"
+	}
+	return fmt.Sprintf("%s\n%s", e.ytdlp.Error(), hint)
+}
+
+func (e *authError) Unwrap() error { return e.ytdlp }
+
+// newYtdlpError wraps a failed yt-dlp run, as an authError if signing in
+// would have helped.
+func newYtdlpError(err error, stderr string) error {
+	yerr := &ytdlpError{err: err, stderr: stderr}
+	if authRequired.MatchString(stderr) {
+		return &authError{ytdlp: yerr}
+	}
+	return yerr
+}
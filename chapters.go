@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// minChapterLength is the shortest chapter tuber keeps, in seconds. YouTube
+// accepts chapters from 10 seconds, but ones that short are rarely a topic
+// of their own. The prompt asks for it and parseChapters enforces it.
+const minChapterLength = 30
+
+// chaptersPrompt asks for chapters in a form parseChapters can read back.
+var chaptersPrompt = "Split this transcript of a YouTube video into chapters, the way a creator would in the video's description. " +
+	"Each transcript line starts with the [mm:ss] time it's said. Reply with one chapter per line and nothing else, " +
+	"each line being the [mm:ss] time the chapter starts, copied from the transcript, then a short title, e.g. " +
+	"\"[4:05] Setting up the router\". Start the first chapter at [0:00]. Aim for one chapter per topic, " +
+	fmt.Sprintf("usually 3 to 15 chapters, none shorter than %d seconds.", minChapterLength)
+
+// chapterLine matches a line of the LLM's reply: "[4:05] Title", allowing
+// for list markers and a separator after the time.
+var chapterLine = regexp.MustCompile(`^[\s*•-]*(?:\d+[.)]\s*)?\[?(\d+:\d\d(?::\d\d)?)\]?\s*[-–—:]?\s*(.+)$`)
+
+// parseChapters reads chapters out of the LLM's reply. Times past the end
+// of the video and chapters too short to keep are dropped; the first
+// chapter is moved to 0:00 since that's where YouTube expects one. End
+// times run to the next chapter, or to duration for the last one.
+func parseChapters(text string, duration float64) []videoChapter {
+	var chapters []videoChapter
+	for _, line := range strings.Split(text, "\n") {
+		m := chapterLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		start, ok := parseVTTTime(m[1])
+		title := strings.Trim(strings.TrimSpace(m[2]), `"*`)
+		if !ok || title == "" || (duration > 0 && start >= duration) {
+			continue
+		}
+		chapters = append(chapters, videoChapter{Start: start, Title: title})
+	}
+	if len(chapters) == 0 {
+		return nil
+	}
+
+	sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].Start < chapters[j].Start })
+	chapters[0].Start = 0
+	kept := chapters[:1]
+	for _, c := range chapters[1:] {
+		if c.Start-kept[len(kept)-1].Start >= minChapterLength {
+			kept = append(kept, c)
+		}
+	}
+	for i := range kept {
+		if i+1 < len(kept) {
+			kept[i].End = kept[i+1].Start
+		} else {
+			kept[i].End = max(duration, kept[i].Start)
+		}
+	}
+	return kept
+}
+
+// generateChapters asks the LLM to split a transcript into chapters.
+func generateChapters(ctx context.Context, cues []cue, duration float64) ([]videoChapter, error) {
+	if duration == 0 && len(cues) > 0 {
+		duration = cues[len(cues)-1].End
+	}
+	req := llmRequest{Prompt: chaptersPrompt, Context: timestampedTranscript(cues)}
+	reply, err := newLLM(cfg.LLM).complete(ctx, req, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+	chapters := parseChapters(reply, duration)
+	if len(chapters) < 2 {
+		return nil, errors.New("couldn't split the transcript into chapters")
+	}
+	return chapters, nil
+}
+
+// videoChapters returns a video's chapters: the ones it already has, or
+// if there aren't any (or regenerate is set), ones made from its
+// transcript. yt-dlp's progress goes to log.
+func videoChapters(ctx context.Context, url string, regenerate bool, log io.Writer) (*videoInfo, []videoChapter, error) {
+	info, err := fetchInfo(ctx, url)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(info.Chapters) > 0 && !regenerate {
+		fmt.Fprintln(log, "📑 Using the video's own chapters")
+		return info, info.Chapters, nil
+	}
+	if !llmAvailable {
+		return nil, nil, fmt.Errorf("this video has no chapters, and making them needs an LLM: %v", llmErr)
+	}
+
+	fmt.Fprintln(log, "📝 Fetching subtitles for chapters...")
+	_, cues, err := fetchCues(ctx, url, log)
+	if err != nil {
+		return nil, nil, err
+	}
+	fmt.Fprint(log, "\n🤖 Generating chapters...\n\n")
+	chapters, err := generateChapters(ctx, cues, info.Duration)
+	if err != nil {
+		return nil, nil, err
+	}
+	return info, chapters, nil
+}
+
+// chapterList renders chapters the way YouTube reads them from a video
+// description: "0:00 Intro", one per line.
+func chapterList(chapters []videoChapter) string {
+	var b strings.Builder
+	for _, c := range chapters {
+		fmt.Fprintf(&b, "%s %s\n", formatDuration(c.Start), c.Title)
+	}
+	return b.String()
+}
+
+// ffmetadata renders chapters as an FFmetadata file, which ffmpeg (and
+// so most tools that add chapters to files) can read.
+func ffmetadata(chapters []videoChapter) string {
+	escape := strings.NewReplacer(`\`, `\\`, "=", `\=`, ";", `\;`, "#", `\#`, "\n", "\\\n")
+	var b strings.Builder
+	b.WriteString(";FFMETADATA1\n")
+	for _, c := range chapters {
+		fmt.Fprintf(&b, "\n[CHAPTER]\nTIMEBASE=1/1000\nSTART=%d\nEND=%d\ntitle=%s\n",
+			int64(c.Start*1000), int64(c.End*1000), escape.Replace(c.Title))
+	}
+	return b.String()
+}
+
+// chapterFile reports whether chapters can be embedded in path.
+func chapterFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp4", ".m4a", ".mkv", ".mp3":
+		return true
+	}
+	return false
+}
+
+// embedChapters writes the chapters in the FFmetadata file metaPath into
+// the media file at path, replacing any it already has. The streams are
+// copied, not re-encoded, so it only takes as long as copying the file.
+func embedChapters(ctx context.Context, path, metaPath string) error {
+	if _, err := exec.LookPath("ffmpeg"); err != nil {
+		return errors.New("embedding chapters needs ffmpeg in PATH")
+	}
+
+	// name.temp.ext keeps the extension ffmpeg picks the format from, and
+	// is cleaned up with the other partial files if we're cancelled
+	ext := filepath.Ext(path)
+	tmp := strings.TrimSuffix(path, ext) + ".temp" + ext
+
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-y", "-loglevel", "error",
+		"-i", path, "-i", metaPath,
+		"-map", "0", "-map_metadata", "0", "-map_chapters", "1",
+		"-c", "copy",
+		tmp,
+	)
+	setProcessGroup(cmd)
+	cmd.WaitDelay = 5 * time.Second
+	if out, err := cmd.CombinedOutput(); err != nil {
+		os.Remove(tmp)
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+			return fmt.Errorf("ffmpeg: %s", last)
+		}
+		return fmt.Errorf("ffmpeg: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// writeChapters is the chapters step of a run: it gets the video's
+// chapters, prints them, saves them next to the downloaded files as a
+// description-style list and an FFmetadata file, and embeds them into any
+// video or audio the run downloaded. It returns the files it wrote.
+func writeChapters(ctx context.Context, url string, downloaded []string) ([]string, error) {
+	info, chapters, err := videoChapters(ctx, url, false, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+
+	// Name the files after what was downloaded, so they sort together
+	var base string
+	switch {
+	case len(downloaded) > 0:
+		base = strings.TrimSuffix(downloaded[0], filepath.Ext(downloaded[0]))
+	case customOutPath != "" && !strings.Contains(customOutPath, "%("):
+		base = customOutPath
+	default:
+		base = filepath.Join(outputSearchDir(), sanitizeFilename(info.Title))
+	}
+
+	listPath := base + ".chapters.txt"
+	metaPath := base + ".ffmetadata"
+	if err := os.WriteFile(listPath, []byte(chapterList(chapters)), 0644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(metaPath, []byte(ffmetadata(chapters)), 0644); err != nil {
+		return []string{listPath}, err
+	}
+	written := []string{listPath, metaPath}
+
+	// Chapters go to stdout, like a summary
+	fmt.Print(chapterList(chapters))
+
+	for _, f := range downloaded {
+		if !chapterFile(f) {
+			continue
+		}
+		if err := embedChapters(ctx, f, metaPath); err != nil {
+			return written, fmt.Errorf("couldn't add chapters to %s: %w", filepath.Base(f), err)
+		}
+		fmt.Fprintf(os.Stderr, "📑 Added chapters to %s\n", filepath.Base(f))
+	}
+	return written, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseChapters(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		duration float64
+		want     []videoChapter
+	}{
+		{
+			name:     "plain",
+			text:     "[0:00] Intro\n[1:30] Setup\n[1:02:05] Wrap-up",
+			duration: 3800,
+			want:     []videoChapter{{0, 90, "Intro"}, {90, 3725, "Setup"}, {3725, 3800, "Wrap-up"}},
+		},
+		{
+			name:     "list markers and separators",
+			text:     "Here are the chapters:\n- [0:05] - **Intro**\n2. 2:00: \"Setup\"\n* [4:00] — Demo\n",
+			duration: 300,
+			want:     []videoChapter{{0, 120, "Intro"}, {120, 240, "Setup"}, {240, 300, "Demo"}},
+		},
+		{
+			name:     "out of order",
+			text:     "[2:00] Second\n[0:00] First",
+			duration: 200,
+			want:     []videoChapter{{0, 120, "First"}, {120, 200, "Second"}},
+		},
+		{
+			name:     "too short and past the end",
+			text:     "[0:00] Intro\n[0:20] Too soon\n[1:00] Main\n[5:00] After the end",
+			duration: 240,
+			want:     []videoChapter{{0, 60, "Intro"}, {60, 240, "Main"}},
+		},
+		{
+			name: "nothing usable",
+			text: "I can't split this transcript.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseChapters(tt.text, tt.duration); !slices.Equal(got, tt.want) {
+				t.Errorf("parseChapters() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChaptersPromptMinLength(t *testing.T) {
+	if !strings.Contains(chaptersPrompt, fmt.Sprintf("none shorter than %d seconds", minChapterLength)) {
+		t.Errorf("chaptersPrompt doesn't ask for chapters of at least minChapterLength: %q", chaptersPrompt)
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// askPrompt sets up the LLM to answer questions from a timestamped
+// transcript.
+const askPrompt = "You answer questions about a YouTube video using only its transcript, which is provided. " +
+	"Each transcript line starts with the [mm:ss] time it's said. Back up each point in your answer by " +
+	"citing the time it comes from in the same [mm:ss] form, e.g. [4:05]. If the transcript doesn't " +
+	"cover the question, say so. Keep answers short and plain text."
+
+// askRequest builds the request for the next answer in a conversation.
+func askRequest(title string, cues []cue, msgs []chatMessage) llmRequest {
+	material := "Transcript of \"" + title + "\":\n\n" + timestampedTranscript(cues)
+	return llmRequest{Prompt: askPrompt, Context: material, Messages: msgs}
+}
+
+// Chat screen for asking follow-up questions about one video
+type chatModel struct {
+	ctx      context.Context
+	url      string
+	title    string
+	cues     []cue
+	llm      llmBackend
+	messages []chatMessage
+	input    textinput.Model
+	view     viewport.Model
+	spinner  spinner.Model
+	loading  bool // fetching the transcript
+	thinking bool // waiting for an answer
+	err      error
+	width    int
+}
+
+type cuesMsg struct {
+	title string
+	cues  []cue
+}
+type answerMsg struct {
+	text string
+	err  error
+}
+
+var (
+	questionStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("212")).Bold(true)
+	citeStyle     = lipgloss.NewStyle().Foreground(lipgloss.Color("cyan"))
+)
+
+func initialChatModel(ctx context.Context, url, title string) chatModel {
+	input := textinput.New()
+	input.Prompt = "> "
+	input.Placeholder = "Ask something about the video"
+	input.PromptStyle = editStyle
+	input.Focus()
+
+	s := spinner.New()
+	s.Spinner = spinner.Dot
+	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
+
+	return chatModel{
+		ctx:     ctx,
+		url:     url,
+		title:   title,
+		llm:     newLLM(cfg.LLM),
+		input:   input,
+		view:    viewport.New(80, 20),
+		spinner: s,
+		loading: true,
+		width:   80,
+	}
+}
+
+func (m chatModel) Init() tea.Cmd {
+	return tea.Batch(m.spinner.Tick, textinput.Blink, func() tea.Msg {
+		title, cues, err := fetchCues(m.ctx, m.url, io.Discard)
+		if err != nil {
+			return answerMsg{err: err}
+		}
+		return cuesMsg{title: title, cues: cues}
+	})
+}
+
+func (m chatModel) ask() tea.Cmd {
+	req := askRequest(m.title, m.cues, m.messages)
+	return func() tea.Msg {
+		text, err := m.llm.complete(m.ctx, req, io.Discard)
+		return answerMsg{text: strings.TrimSpace(text), err: err}
+	}
+}
+
+func (m chatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.width = msg.Width
+		m.view.Width = msg.Width
+		m.view.Height = max(msg.Height-5, 3)
+		m.input.Width = msg.Width - 4
+		m.refresh()
+		return m, nil
+
+	case cuesMsg:
+		m.loading = false
+		if m.title == "" {
+			m.title = msg.title
+		}
+		m.cues = msg.cues
+		m.refresh()
+		return m, nil
+
+	case answerMsg:
+		m.loading = false
+		m.thinking = false
+		if msg.err != nil {
+			if m.ctx.Err() != nil {
+				return m, tea.Quit
+			}
+			m.err = msg.err
+			if len(m.cues) == 0 {
+				// Nothing to talk about without a transcript
+				return m, tea.Quit
+			}
+			// Drop the unanswered question so it can be asked again
+			m.messages = m.messages[:len(m.messages)-1]
+		} else {
+			m.err = nil
+			m.messages = append(m.messages, chatMessage{Role: "assistant", Content: msg.text})
+		}
+		m.refresh()
+		return m, nil
+
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+
+	case tea.KeyMsg:
+		switch msg.String() {
+		case "ctrl+c", "esc":
+			return m, tea.Quit
+		case "pgup", "pgdown":
+			var cmd tea.Cmd
+			m.view, cmd = m.view.Update(msg)
+			return m, cmd
+		case "enter":
+			question := strings.TrimSpace(m.input.Value())
+			if question == "" || m.loading || m.thinking {
+				return m, nil
+			}
+			m.input.Reset()
+			m.messages = append(m.messages, chatMessage{Role: "user", Content: question})
+			m.thinking = true
+			m.err = nil
+			m.refresh()
+			return m, m.ask()
+		}
+	}
+
+	var cmd tea.Cmd
+	m.input, cmd = m.input.Update(msg)
+	return m, cmd
+}
+
+// refresh re-renders the conversation into the viewport and scrolls to
+// the latest message.
+func (m *chatModel) refresh() {
+	wrap := lipgloss.NewStyle().Width(max(m.width-2, 20))
+	var b strings.Builder
+	for _, msg := range m.messages {
+		if msg.Role == "user" {
+			b.WriteString(questionStyle.Render(wrap.Render("> "+msg.Content)) + "\n\n")
+			continue
+		}
+		answer := timestampRef.ReplaceAllStringFunc(wrap.Render(msg.Content), func(ts string) string {
+			return citeStyle.Render(ts)
+		})
+		b.WriteString(answer + "\n\n")
+	}
+	m.view.SetContent(b.String())
+	m.view.GotoBottom()
+}
+
+func (m chatModel) View() string {
+	if m.loading {
+		return m.spinner.View() + " Fetching transcript..."
+	}
+
+	s := titleStyle.Render(m.title) + "\n"
+	s += m.view.View() + "\n"
+	switch {
+	case m.thinking:
+		s += m.spinner.View() + " Thinking...\n"
+	case m.err != nil:
+		s += errorStyle.Render("✗ "+m.err.Error()) + "\n"
+	default:
+		s += "\n"
+	}
+	s += m.input.View() + "\n"
+	s += dimStyle.Render("enter ask • pgup/pgdown scroll • esc quit")
+	return s
+}
+
+// runChat opens the chat screen for a video.
+func runChat(ctx context.Context, url, title string) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p := tea.NewProgram(initialChatModel(ctx, url, title), tea.WithAltScreen())
+	final, err := p.Run()
+	if err != nil {
+		return err
+	}
+	if cm := final.(chatModel); len(cm.cues) == 0 && cm.err != nil {
+		return cm.err
+	}
+	return nil
+}
+
+// askOnce answers a single question, printing the answer to stdout.
+func askOnce(ctx context.Context, url, question string) error {
+	fmt.Fprintln(os.Stderr, "📝 Fetching subtitles...")
+	title, cues, err := fetchCues(ctx, url, os.Stderr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprint(os.Stderr, "\n🤖 Thinking...\n\n")
+
+	msgs := []chatMessage{{Role: "user", Content: question}}
+	_, err = newLLM(cfg.LLM).complete(ctx, askRequest(title, cues, msgs), os.Stdout)
+	return err
+}
@@ -0,0 +1,1183 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// A command is one `tuber <name>` subcommand.
+type command struct {
+	name    string
+	args    string // argument synopsis for usage, e.g. "[flags] <url>"
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+func commandList() []command {
+	return []command{
+		{"get", "[flags] <url> [-- yt-dlp args]", "Download video, audio, subtitles and/or a summary", runGet},
+		{"summarize", "[flags] <url> [-- yt-dlp args]", "Summarize a video using AI", runSummarize},
+		{"ask", "[flags] <url> [-- yt-dlp args]", "Ask questions about a video's transcript", runAsk},
+		{"transcript", "[flags] <url> [-- yt-dlp args]", "Print a video's transcript", runTranscript},
+		{"chapters", "[flags] <url> [-- yt-dlp args]", "Show a video's chapters, generating them if it has none", runChapters},
+		{"info", "[flags] <url> [-- yt-dlp args]", "Show what a URL contains without downloading", runInfo},
+		{"search", "[flags] <query> | -reindex [dir...]", "Search downloaded transcripts", runSearch},
+		{"export", "[flags] <channel or playlist url> [-- yt-dlp args]", "Save every transcript in a channel or playlist, without the media", runExport},
+		{"config", "[get <key> | set <key> <value> | unset <key> | path]", "Show or change default settings", runConfig},
+		{"history", "[flags]", "List past runs", runHistory},
+		{"resume", "[flags] [id...]", "Finish downloads cut short by a crash, reboot or closed terminal", runResume},
+		{"digest", "[flags]", "Collect recent summaries into one Markdown or HTML page", runDigest},
+		{"feed", "[flags] [dir]", "Make a podcast feed of the audio in a directory, and optionally serve it", runFeed},
+		{"serve", "[flags]", "Run an HTTP API that queues downloads", runServe},
+		{"watch", "[flags] | add [flags] <url> | list | remove <name or url>", "Download new uploads from channels and playlists", runWatch},
+	}
+}
+
+func findCommand(name string) (command, bool) {
+	for _, c := range commandList() {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return command{}, false
+}
+
+var (
+	// errUsage means the command line was wrong; usage has been printed.
+	errUsage = errors.New("usage")
+	// errHelp means help was asked for and printed.
+	errHelp = errors.New("help")
+)
+
+func printUsage() {
+	w := os.Stderr
+	fmt.Fprintln(w, "Usage:")
+	fmt.Fprintln(w, "  tuber [url]                 Open the interactive menu")
+	fmt.Fprintln(w, "  tuber <command> [arguments]")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, c := range commandList() {
+		fmt.Fprintf(w, "  %-12s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w, "\nRun 'tuber <command> -h' for help on a command.")
+	fmt.Fprintln(w, "\nExamples:")
+	fmt.Fprintln(w, "  tuber get -a -s <url>                    Download audio and subtitles")
+	fmt.Fprintln(w, "  tuber summarize -p \"List key points\" <url>  Summarize with custom prompt")
+	fmt.Fprintln(w, "  tuber get -v <url> -- video: -S res:720     Pass extra args to yt-dlp, here just for the video")
+}
+
+// dispatch runs the subcommand named by args[0], or the interactive menu
+// when there isn't one.
+func dispatch(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return runMenu(ctx, "")
+	}
+
+	switch args[0] {
+	case "-h", "-help", "--help", "help":
+		printUsage()
+		return nil
+	}
+
+	if c, ok := findCommand(args[0]); ok {
+		return c.run(ctx, args[1:])
+	}
+
+	// Old-style `tuber -a -s <url>` still works as `tuber get`
+	if strings.HasPrefix(args[0], "-") {
+		return runGet(ctx, args)
+	}
+
+	if len(args) > 1 {
+		printUsage()
+		return errUsage
+	}
+	return runMenu(ctx, args[0])
+}
+
+// newFlagSet returns a flag set whose -h output describes c.
+func newFlagSet(c command) *flag.FlagSet {
+	fs := flag.NewFlagSet(c.name, flag.ContinueOnError)
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "Usage: tuber %s %s\n\n%s\n", c.name, c.args, c.summary)
+		hasFlags := false
+		fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+		if hasFlags {
+			fmt.Fprintln(w, "\nFlags:")
+			fs.PrintDefaults()
+		}
+	}
+	return fs
+}
+
+// parseFlags parses args into fs, mapping -h to errHelp and bad flags to
+// errUsage.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return errHelp
+		}
+		return errUsage
+	}
+	// Network flags can be set in ways that can't work together
+	return cfg.Network.check()
+}
+
+// urlArg returns the single URL argument left after flag parsing,
+// normalized.
+func urlArg(fs *flag.FlagSet) (string, error) {
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return "", errUsage
+	}
+	url, err := normalizeURL(fs.Arg(0))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", fs.Arg(0), err)
+	}
+	return url, nil
+}
+
+func requireYtdlp() error {
+	if _, err := exec.LookPath("yt-dlp"); err != nil {
+		return errors.New("yt-dlp not found in PATH\nInstall it from: https://github.com/yt-dlp/yt-dlp")
+	}
+	return nil
+}
+
+func requireLLM() error {
+	if llmAvailable {
+		return nil
+	}
+	if cfg.LLM.Backend == "" || cfg.LLM.Backend == "claude" {
+		return errors.New("this needs the claude cli\nInstall it from: https://claude.ai/download")
+	}
+	return fmt.Errorf("this needs an LLM: %v", llmErr)
+}
+
+// addRunFlags registers the flags shared by every command that downloads.
+func addRunFlags(fs *flag.FlagSet) {
+	fs.BoolVar(&keepPartial, "keep-partial", cfg.KeepPartial, "Keep partial downloads when cancelled")
+	fs.IntVar(&maxAttempts, "attempts", maxAttempts, "Max attempts per step for transient failures")
+	addRateFlags(fs)
+	addAuthFlags(fs)
+	addNetworkFlags(fs)
+}
+
+// addStepFlags registers the flags that pick what a download does. The
+// returned func builds the options once the flags are parsed.
+func addStepFlags(fs *flag.FlagSet) func() (DownloadOptions, error) {
+	videoFlag := fs.Bool("v", false, "Download video")
+	audioFlag := fs.Bool("a", false, "Download audio (mp3)")
+	subsFlag := fs.Bool("s", false, "Download subtitles (text)")
+	sumFlag := fs.Bool("sum", false, "Summarize video using AI")
+	chaptersFlag := fs.Bool("chapters", false, "Save chapters (generated if the video has none) and add them to the video/audio")
+	promptFlag := fs.String("p", "", "Custom prompt for summary")
+	presetFlag := addPresetFlag(fs)
+	embed := cfg.Embed
+	fs.Var(embedFlag{&embed}, "embed", "Embed a comma-separated `list` in the video/audio: "+strings.Join(embedNames, ", ")+", all or none")
+
+	return func() (DownloadOptions, error) {
+		prompt, err := pickPrompt(*promptFlag, *presetFlag)
+		if err != nil {
+			return DownloadOptions{}, err
+		}
+		return DownloadOptions{
+			Video:    *videoFlag,
+			Audio:    *audioFlag,
+			Subs:     *subsFlag,
+			Summary:  *sumFlag,
+			Chapters: *chaptersFlag,
+			Prompt:   prompt,
+			Embed:    embed,
+		}, nil
+	}
+}
+
+func runGet(ctx context.Context, args []string) error {
+	c, _ := findCommand("get")
+	fs := newFlagSet(c)
+	stepOptions := addStepFlags(fs)
+	fs.StringVar(&outputDir, "o", cfg.OutputDir, "Output directory (default: current directory)")
+	addRunFlags(fs)
+	if err := parseRunFlags(fs, args); err != nil {
+		return err
+	}
+
+	opts, err := stepOptions()
+	if err != nil {
+		return err
+	}
+
+	// Without any step flags, pick interactively
+	if opts.isEmpty() {
+		if fs.NArg() > 1 {
+			fs.Usage()
+			return errUsage
+		}
+		return runMenu(ctx, fs.Arg(0))
+	}
+
+	url, err := urlArg(fs)
+	if err != nil {
+		return err
+	}
+	if err := requireYtdlp(); err != nil {
+		return err
+	}
+	if opts.Summary {
+		if err := requireLLM(); err != nil {
+			return err
+		}
+	}
+	return runAndReport(ctx, url, nil, opts)
+}
+
+func runSummarize(ctx context.Context, args []string) error {
+	c, _ := findCommand("summarize")
+	fs := newFlagSet(c)
+	promptFlag := fs.String("p", "", "Prompt for the summary (default: the configured prompt)")
+	presetFlag := addPresetFlag(fs)
+	addRunFlags(fs)
+	if err := parseRunFlags(fs, args); err != nil {
+		return err
+	}
+	prompt, err := pickPrompt(*promptFlag, *presetFlag)
+	if err != nil {
+		return err
+	}
+	url, err := urlArg(fs)
+	if err != nil {
+		return err
+	}
+	if err := requireYtdlp(); err != nil {
+		return err
+	}
+	if err := requireLLM(); err != nil {
+		return err
+	}
+	return runAndReport(ctx, url, nil, DownloadOptions{Summary: true, Prompt: prompt})
+}
+
+func runAsk(ctx context.Context, args []string) error {
+	c, _ := findCommand("ask")
+	fs := newFlagSet(c)
+	questionFlag := fs.String("q", "", "Answer this one question and exit instead of opening the chat screen")
+	addRunFlags(fs)
+	if err := parseRunFlags(fs, args); err != nil {
+		return err
+	}
+	url, err := urlArg(fs)
+	if err != nil {
+		return err
+	}
+	if err := requireYtdlp(); err != nil {
+		return err
+	}
+	if err := requireLLM(); err != nil {
+		return err
+	}
+
+	if *questionFlag != "" {
+		return askOnce(ctx, url, *questionFlag)
+	}
+	return runChat(ctx, url, "")
+}
+
+// addPresetFlag registers -preset, listing the available presets in its help.
+func addPresetFlag(fs *flag.FlagSet) *string {
+	return fs.String("preset", "", "Use a saved summary prompt: "+strings.Join(cfg.presetNames(), ", "))
+}
+
+// pickPrompt resolves the -p and -preset flags to a summary prompt.
+func pickPrompt(prompt, preset string) (string, error) {
+	switch {
+	case prompt != "" && preset != "":
+		return "", errors.New("use -p or -preset, not both")
+	case preset != "":
+		return cfg.preset(preset)
+	case prompt != "":
+		return prompt, nil
+	}
+	return cfg.prompt(), nil
+}
+
+func runTranscript(ctx context.Context, args []string) error {
+	c, _ := findCommand("transcript")
+	fs := newFlagSet(c)
+	outFlag := fs.String("o", "", "Write <title>.txt into this directory instead of printing")
+	addRunFlags(fs)
+	if err := parseRunFlags(fs, args); err != nil {
+		return err
+	}
+	url, err := urlArg(fs)
+	if err != nil {
+		return err
+	}
+	if err := requireYtdlp(); err != nil {
+		return err
+	}
+
+	if *outFlag != "" {
+		outputDir = *outFlag
+		return runAndReport(ctx, url, nil, DownloadOptions{Subs: true})
+	}
+
+	transcript, err := fetchTranscript(ctx, url)
+	if err != nil {
+		return err
+	}
+	fmt.Println(transcript)
+	return nil
+}
+
+func runChapters(ctx context.Context, args []string) error {
+	c, _ := findCommand("chapters")
+	fs := newFlagSet(c)
+	metaFlag := fs.String("ffmetadata", "", "Also write the chapters to this file in FFmetadata format")
+	embedFlag := fs.String("embed", "", "Add the chapters to this downloaded mp4/mp3 file")
+	regenerateFlag := fs.Bool("regenerate", false, "Make new chapters even if the video has its own")
+	addRunFlags(fs)
+	if err := parseRunFlags(fs, args); err != nil {
+		return err
+	}
+	url, err := urlArg(fs)
+	if err != nil {
+		return err
+	}
+	if err := requireYtdlp(); err != nil {
+		return err
+	}
+	if *regenerateFlag {
+		if err := requireLLM(); err != nil {
+			return err
+		}
+	}
+	if *embedFlag != "" {
+		if !chapterFile(*embedFlag) {
+			return fmt.Errorf("%s: can only add chapters to mp4, m4a, mkv and mp3 files", *embedFlag)
+		}
+		if _, err := os.Stat(*embedFlag); err != nil {
+			return err
+		}
+	}
+
+	_, chapters, err := videoChapters(ctx, url, *regenerateFlag, os.Stderr)
+	if err != nil {
+		return err
+	}
+	fmt.Print(chapterList(chapters))
+
+	metaPath := *metaFlag
+	if metaPath == "" && *embedFlag != "" {
+		// ffmpeg reads the chapters from a file, so make a throwaway one
+		f, err := os.CreateTemp("", "tuber-*.ffmetadata")
+		if err != nil {
+			return err
+		}
+		f.Close()
+		defer os.Remove(f.Name())
+		metaPath = f.Name()
+	}
+	if metaPath != "" {
+		if err := os.WriteFile(metaPath, []byte(ffmetadata(chapters)), 0644); err != nil {
+			return err
+		}
+	}
+	if *embedFlag != "" {
+		if err := embedChapters(ctx, *embedFlag, metaPath); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "📑 Added chapters to %s\n", *embedFlag)
+	}
+	return nil
+}
+
+func runInfo(ctx context.Context, args []string) error {
+	c, _ := findCommand("info")
+	fs := newFlagSet(c)
+	jsonFlag := fs.Bool("json", false, "Print the normalized metadata as JSON")
+	addRunFlags(fs)
+	if err := parseRunFlags(fs, args); err != nil {
+		return err
+	}
+	url, err := urlArg(fs)
+	if err != nil {
+		return err
+	}
+	if err := requireYtdlp(); err != nil {
+		return err
+	}
+
+	info, err := fetchInfo(ctx, url)
+	if err != nil {
+		return err
+	}
+	if *jsonFlag {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(info)
+	}
+	printInfo(os.Stdout, info)
+	return nil
+}
+
+func runSearch(ctx context.Context, args []string) error {
+	c, _ := findCommand("search")
+	fs := newFlagSet(c)
+	limitFlag := fs.Int("n", 10, "Show at most this many videos (0 for all)")
+	linesFlag := fs.Int("lines", 3, "Matching lines to show per video")
+	jsonFlag := fs.Bool("json", false, "Print results as JSON lines")
+	reindexFlag := fs.Bool("reindex", false, "Add transcripts from the history and the given directories (default: the output directory) to the index, and drop deleted ones")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *reindexFlag {
+		dirs := fs.Args()
+		if len(dirs) == 0 {
+			dirs = []string{cmp.Or(cfg.OutputDir, ".")}
+		}
+		added, removed, err := reindex(dirs)
+		if err != nil {
+			return err
+		}
+		msg := "✓ Indexed " + plural(added, "transcript")
+		if removed > 0 {
+			msg += fmt.Sprintf(", dropped %d deleted", removed)
+		}
+		fmt.Fprintln(os.Stderr, msg)
+		return nil
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return errUsage
+	}
+	query := parseQuery(strings.Join(fs.Args(), " "))
+	if len(query.Terms) == 0 {
+		return errors.New("nothing to search for")
+	}
+	idx, err := loadIndex()
+	if err != nil {
+		return err
+	}
+	if len(idx.Docs) == 0 {
+		return errors.New("no transcripts indexed yet: download some with tuber get -s, or run tuber search -reindex")
+	}
+
+	hits := idx.search(query, *linesFlag)
+	if *limitFlag > 0 && len(hits) > *limitFlag {
+		hits = hits[:*limitFlag]
+	}
+	if *jsonFlag {
+		enc := json.NewEncoder(os.Stdout)
+		for _, hit := range hits {
+			if err := enc.Encode(hit); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(hits) == 0 {
+		fmt.Fprintln(os.Stderr, "No matches")
+		return nil
+	}
+	printHits(os.Stdout, hits, query)
+	return nil
+}
+
+func runHistory(ctx context.Context, args []string) error {
+	c, _ := findCommand("history")
+	fs := newFlagSet(c)
+	limitFlag := fs.Int("n", 20, "Show at most this many runs (0 for all)")
+	queryFlag := fs.String("q", "", "Only runs whose title or URL contains this")
+	outcomeFlag := fs.String("outcome", "", "Only runs that ended this way: done, cancelled or failed")
+	jsonFlag := fs.Bool("json", false, "Print entries as JSON lines")
+	rerunFlag := fs.Int("rerun", 0, "Run the entry with this ID again")
+	addRunFlags(fs)
+	if err := parseRunFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	if *rerunFlag != 0 {
+		e, ok, err := findHistory(*rerunFlag)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("no history entry %d", *rerunFlag)
+		}
+		if err := requireYtdlp(); err != nil {
+			return err
+		}
+		if e.Options.Summary {
+			if err := requireLLM(); err != nil {
+				return err
+			}
+		}
+		outputDir = e.OutputDir
+		customOutPath = e.OutPath
+		return runAndReport(ctx, e.URL, &videoInfo{Title: e.Title, Channel: e.Channel, Duration: e.Duration}, e.Options)
+	}
+
+	entries, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	var shown []historyEntry
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if *queryFlag != "" && !e.matches(*queryFlag) {
+			continue
+		}
+		if *outcomeFlag != "" && e.Outcome != *outcomeFlag {
+			continue
+		}
+		shown = append(shown, e)
+		if *limitFlag > 0 && len(shown) == *limitFlag {
+			break
+		}
+	}
+
+	if *jsonFlag {
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range shown {
+			if err := enc.Encode(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if len(shown) == 0 {
+		fmt.Fprintln(os.Stderr, "No history yet")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tWHEN\tOUTCOME\tWHAT\tTITLE")
+	for _, e := range shown {
+		title := e.Title
+		if title == "" {
+			title = e.URL
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", e.ID, e.Time.Local().Format("2006-01-02 15:04"), e.Outcome, e.Options, title)
+	}
+	return tw.Flush()
+}
+
+func runResume(ctx context.Context, args []string) error {
+	c, _ := findCommand("resume")
+	fs := newFlagSet(c)
+	listFlag := fs.Bool("list", false, "List unfinished runs instead of resuming them")
+	discardFlag := fs.Bool("discard", false, "Forget the given runs, and their partial files, instead of resuming them")
+	addRunFlags(fs)
+	if err := parseRunFlags(fs, args); err != nil {
+		return err
+	}
+	if *discardFlag && fs.NArg() == 0 {
+		return errors.New("-discard needs the IDs of the runs to forget (see tuber resume -list)")
+	}
+
+	runs, err := loadPendingRuns()
+	if err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		var picked []*pendingRun
+		for _, id := range fs.Args() {
+			i := slices.IndexFunc(runs, func(p *pendingRun) bool { return p.ID == id })
+			if i < 0 {
+				return fmt.Errorf("no unfinished run %s", id)
+			}
+			picked = append(picked, runs[i])
+		}
+		runs = picked
+	}
+	if len(runs) == 0 {
+		fmt.Fprintln(os.Stderr, "Nothing to resume")
+		return nil
+	}
+
+	if *listFlag {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tSTARTED\tDONE\tLEFT\tTITLE")
+		for _, p := range runs {
+			title := cmp.Or(p.Title, p.URL)
+			if p.running() {
+				title += fmt.Sprintf(" (still running, pid %d)", p.PID)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", p.ID, p.Started.Local().Format("2006-01-02 15:04"),
+				cmp.Or(strings.Join(p.Completed, ","), "-"), strings.Join(p.remaining(), ","), title)
+		}
+		return tw.Flush()
+	}
+
+	if *discardFlag {
+		for _, p := range runs {
+			if p.running() {
+				return fmt.Errorf("run %s is still running (pid %d)", p.ID, p.PID)
+			}
+			if !keepPartial {
+				outputDir, customOutPath = p.OutputDir, p.OutPath
+				removePartials(outputSearchDir(), p.Started)
+			}
+			p.remove()
+		}
+		return nil
+	}
+
+	if err := requireYtdlp(); err != nil {
+		return err
+	}
+	var failed int
+	for i, p := range runs {
+		if p.running() {
+			fmt.Fprintf(os.Stderr, "Skipping %s, it's still running (pid %d)\n", p.ID, p.PID)
+			continue
+		}
+		if p.Options.without(p.Completed).Summary {
+			if err := requireLLM(); err != nil {
+				return err
+			}
+		}
+		if i > 0 {
+			if err := pause(ctx, p.URL); err != nil {
+				return err
+			}
+		}
+		outputDir, customOutPath = p.OutputDir, p.OutPath
+		fmt.Fprintf(os.Stderr, "\nResuming %s from:\n%s\n\n", p.Options.without(p.Completed), p.URL)
+		if err := runPending(ctx, p); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%s failed", plural(failed, "run"))
+	}
+	return nil
+}
+
+func runWatch(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "add":
+			return runWatchAdd(args[1:])
+		case "list":
+			return runWatchList(args[1:])
+		case "remove":
+			return runWatchRemove(args[1:])
+		}
+	}
+
+	c, _ := findCommand("watch")
+	fs := newFlagSet(c)
+	onceFlag := fs.Bool("once", false, "Check once and exit instead of polling")
+	intervalFlag := fs.Duration("interval", 0, "Time between checks (default: the subscriptions file's interval, or 1h)")
+	addRunFlags(fs)
+	if err := parseRunFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errUsage
+	}
+	if err := requireYtdlp(); err != nil {
+		return err
+	}
+	return watch(ctx, *intervalFlag, *onceFlag)
+}
+
+func runWatchAdd(args []string) error {
+	c := command{name: "watch add", args: "[flags] <channel or playlist url>", summary: "Subscribe to a channel or playlist"}
+	fs := newFlagSet(c)
+	stepOptions := addStepFlags(fs)
+	nameFlag := fs.String("name", "", "Short name to list and remove it by")
+	outFlag := fs.String("o", "", "Output directory (default: the configured one)")
+	templateFlag := fs.String("template", "", "yt-dlp filename template, e.g. \"%(upload_date)s %(title)s\" (default: the title)")
+	backfillFlag := fs.Int("backfill", 0, "Also get this many of the latest uploads already there")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	url, err := urlArg(fs)
+	if err != nil {
+		return err
+	}
+	opts, err := stepOptions()
+	if err != nil {
+		return err
+	}
+	if opts.isEmpty() {
+		return errors.New("say what to do with new uploads: -v, -a, -s, -sum and/or -chapters")
+	}
+	if strings.ContainsAny(*templateFlag, `/\`) {
+		return errors.New("-template is a filename; use -o for the directory")
+	}
+
+	list, err := loadSubscriptions()
+	if err != nil {
+		return err
+	}
+	if list.find(url) >= 0 || (*nameFlag != "" && list.find(*nameFlag) >= 0) {
+		return fmt.Errorf("already subscribed to %s", cmp.Or(*nameFlag, url))
+	}
+	list.Subscriptions = append(list.Subscriptions, subscription{
+		Name:      *nameFlag,
+		URL:       url,
+		Options:   opts,
+		OutputDir: *outFlag,
+		Template:  *templateFlag,
+		Backfill:  *backfillFlag,
+	})
+	if err := saveSubscriptions(list); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "✓ Subscribed to %s (%s)\n", cmp.Or(*nameFlag, url), opts)
+	return nil
+}
+
+// watchUsage prints tuber watch's usage for its subcommands, which don't
+// take flags of their own.
+func watchUsage() error {
+	c, _ := findCommand("watch")
+	newFlagSet(c).Usage()
+	return errUsage
+}
+
+func runWatchList(args []string) error {
+	if len(args) != 0 {
+		return watchUsage()
+	}
+	list, err := loadSubscriptions()
+	if err != nil {
+		return err
+	}
+	if len(list.Subscriptions) == 0 {
+		fmt.Fprintln(os.Stderr, "No subscriptions yet")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tWHAT\tOUTPUT\tURL")
+	for _, s := range list.Subscriptions {
+		out := cmp.Or(s.OutputDir, cfg.OutputDir, ".")
+		if s.Template != "" {
+			out += "/" + s.Template
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", orDash(s.Name), s.Options, out, s.URL)
+	}
+	return tw.Flush()
+}
+
+func runWatchRemove(args []string) error {
+	if len(args) != 1 {
+		return watchUsage()
+	}
+	list, err := loadSubscriptions()
+	if err != nil {
+		return err
+	}
+	i := list.find(args[0])
+	if i < 0 {
+		return fmt.Errorf("not subscribed to %s", args[0])
+	}
+	s := list.Subscriptions[i]
+	list.Subscriptions = slices.Delete(list.Subscriptions, i, i+1)
+	if err := saveSubscriptions(list); err != nil {
+		return err
+	}
+	// Forget what it's seen too, so subscribing again starts afresh
+	if path, err := archivePath(s); err == nil {
+		os.Remove(path)
+	}
+	fmt.Fprintf(os.Stderr, "✓ Unsubscribed from %s\n", s.label())
+	return nil
+}
+
+func runDigest(ctx context.Context, args []string) error {
+	c, _ := findCommand("digest")
+	fs := newFlagSet(c)
+	sinceFlag := fs.String("since", "24h", "Summaries made in this long (e.g. 24h, 7d) or since this date (2006-01-02)")
+	formatFlag := fs.String("format", "", "markdown or html (default: from -o's extension, else markdown)")
+	outFlag := fs.String("o", "", "Write the digest to this file instead of stdout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return errUsage
+	}
+
+	now := time.Now()
+	since, err := parseSince(*sinceFlag, now)
+	if err != nil {
+		return err
+	}
+	format := *formatFlag
+	if format == "" {
+		format = "markdown"
+		if ext := strings.ToLower(filepath.Ext(*outFlag)); ext == ".html" || ext == ".htm" {
+			format = "html"
+		}
+	}
+	write := writeMarkdownDigest
+	switch format {
+	case "markdown", "md":
+	case "html":
+		write = writeHTMLDigest
+	default:
+		return fmt.Errorf("unknown format %q: use markdown or html", format)
+	}
+
+	entries, err := loadHistory()
+	if err != nil {
+		return err
+	}
+	items := digestItems(entries, since)
+	if len(items) == 0 {
+		return fmt.Errorf("no summaries since %s", since.Local().Format("Mon 2 Jan, 15:04"))
+	}
+	fillDigestInfo(ctx, items)
+	groups := groupByChannel(items)
+
+	if *outFlag == "" {
+		return write(os.Stdout, groups, since, now)
+	}
+	f, err := os.Create(*outFlag)
+	if err != nil {
+		return err
+	}
+	if err := write(f, groups, since, now); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "✓ Wrote %s\n", *outFlag)
+	return nil
+}
+
+func runFeed(ctx context.Context, args []string) error {
+	c, _ := findCommand("feed")
+	fs := newFlagSet(c)
+	titleFlag := fs.String("title", "", "Podcast title (default: the directory's name)")
+	baseFlag := fs.String("base-url", "", "URL the directory is served from, for enclosure links (not needed with -serve)")
+	imageFlag := fs.String("image", "", "Podcast artwork: a URL or a file in the directory (default: cover.jpg or folder.jpg if there)")
+	outFlag := fs.String("o", "", "Where to write the feed (default: feed.xml in the directory)")
+	serveFlag := fs.String("serve", "", "Serve the directory and a live feed on this address, e.g. :8080")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return errUsage
+	}
+	dir := cmp.Or(fs.Arg(0), cfg.OutputDir, ".")
+	if info, err := os.Stat(dir); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+
+	opts := feedOptions{
+		Title:   *titleFlag,
+		BaseURL: *baseFlag,
+		Image:   cmp.Or(*imageFlag, feedImage(dir)),
+	}
+	if opts.Title == "" {
+		abs, _ := filepath.Abs(dir)
+		opts.Title = filepath.Base(abs)
+	}
+	if opts.BaseURL != "" && !strings.HasSuffix(opts.BaseURL, "/") {
+		opts.BaseURL += "/"
+	}
+
+	if *serveFlag != "" {
+		return serveFeed(ctx, *serveFlag, dir, opts)
+	}
+	if opts.BaseURL == "" {
+		return errors.New("podcast apps need full URLs: pass -base-url, or use -serve")
+	}
+
+	episodes, err := feedEpisodes(dir)
+	if err != nil {
+		return err
+	}
+	out := cmp.Or(*outFlag, filepath.Join(dir, "feed.xml"))
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	if err := writeFeed(f, episodes, opts); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "✓ Wrote %s with %s\n", out, plural(len(episodes), "episode"))
+	return nil
+}
+
+// parseSince reads a -since value: a duration back from now, with d for
+// days, or a date meaning midnight at its start.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return time.Time{}, fmt.Errorf("-since %q should be a duration like 24h or 7d, or a date like 2006-01-02", s)
+	}
+	return now.Add(-d), nil
+}
+
+func runServe(ctx context.Context, args []string) error {
+	c, _ := findCommand("serve")
+	fs := newFlagSet(c)
+	addrFlag := fs.String("addr", "127.0.0.1:7070", "Address to listen on")
+	originFlag := fs.String("allow-origin", "", "Let pages from this `origin` call the API, e.g. https://www.youtube.com for a bookmarklet")
+	tokenFlag := fs.String("token", os.Getenv("TUBER_TOKEN"), "Require this secret on every request (default $TUBER_TOKEN); worth setting when listening beyond 127.0.0.1")
+	addRunFlags(fs)
+	if err := parseRunFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return errUsage
+	}
+	if err := requireYtdlp(); err != nil {
+		return err
+	}
+	return serve(ctx, *addrFlag, *originFlag, *tokenFlag)
+}
+
+func runExport(ctx context.Context, args []string) error {
+	c, _ := findCommand("export")
+	fs := newFlagSet(c)
+	outFlag := fs.String("o", "", "Directory to write to (default: the output directory)")
+	limitFlag := fs.Int("n", 0, "Only the newest n videos (0 for all)")
+	workersFlag := fs.Int("j", 4, "Fetch this many transcripts at once")
+	transcribeFlag := fs.Bool("transcribe", false, "Transcribe videos without subtitles with transcriber.command (slow)")
+	// A whole channel is a lot of requests, so pace them unless told
+	// otherwise
+	if cfg.RateLimit.PerMinute == 0 {
+		cfg.RateLimit.PerMinute = defaultExportRate
+	}
+	addRunFlags(fs)
+	if err := parseRunFlags(fs, args); err != nil {
+		return err
+	}
+	url, err := urlArg(fs)
+	if err != nil {
+		return err
+	}
+	if err := requireYtdlp(); err != nil {
+		return err
+	}
+	if !*transcribeFlag {
+		cfg.Transcriber.Command = ""
+	}
+
+	opts := exportOptions{
+		Dir:     cmp.Or(*outFlag, cfg.OutputDir, "."),
+		Limit:   *limitFlag,
+		Workers: *workersFlag,
+	}
+	stats, err := exportTranscripts(ctx, url, opts)
+	if stats.Exported+stats.Failed > 0 {
+		msg := fmt.Sprintf("✓ Exported %s to %s", plural(stats.Exported, "transcript"), filepath.Join(opts.Dir, "corpus.jsonl"))
+		if stats.Failed > 0 {
+			msg += fmt.Sprintf(" (%d failed)", stats.Failed)
+		}
+		fmt.Fprintln(os.Stderr, msg)
+	}
+	if err == nil && stats.Exported == 0 && stats.Failed > 0 {
+		return errors.New("no transcripts could be exported")
+	}
+	return err
+}
+
+func runConfig(ctx context.Context, args []string) error {
+	c, _ := findCommand("config")
+	fs := newFlagSet(c)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	args = fs.Args()
+
+	if len(args) == 0 {
+		data, err := json.MarshalIndent(cfg, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	switch {
+	case args[0] == "path" && len(args) == 1:
+		path, err := configPath()
+		if err != nil {
+			return err
+		}
+		fmt.Println(path)
+		return nil
+
+	case args[0] == "get" && len(args) == 2:
+		v, ok := configValue(cfg, args[1])
+		if !ok {
+			return fmt.Errorf("%s is not set", args[1])
+		}
+		if s, ok := v.(string); ok {
+			fmt.Println(s)
+			return nil
+		}
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+
+	case args[0] == "set" && len(args) == 3:
+		updated, err := setConfigValue(cfg, args[1], &args[2])
+		if err != nil {
+			return err
+		}
+		return saveConfig(updated)
+
+	case args[0] == "unset" && len(args) == 2:
+		updated, err := setConfigValue(cfg, args[1], nil)
+		if err != nil {
+			return err
+		}
+		return saveConfig(updated)
+	}
+
+	fs.Usage()
+	return errUsage
+}
+
+// runMenu shows the interactive menu, then runs whatever was picked.
+func runMenu(ctx context.Context, url string) error {
+	if err := requireYtdlp(); err != nil {
+		return err
+	}
+	if url != "" {
+		normalized, err := normalizeURL(url)
+		if err != nil {
+			return fmt.Errorf("%s: %w", url, err)
+		}
+		url = normalized
+	}
+
+	menuCtx, cancelMenu := context.WithCancel(ctx)
+	p := tea.NewProgram(initialModel(menuCtx, url))
+	m, err := p.Run()
+	cancelMenu()
+	if err != nil {
+		return err
+	}
+
+	finalModel := m.(model)
+	if finalModel.quitting {
+		return nil
+	}
+	if finalModel.asking {
+		return runChat(ctx, finalModel.url, finalModel.title)
+	}
+	customOutPath = finalModel.outPath
+	return runAndReport(ctx, finalModel.url, finalModel.info, finalModel.getOptions())
+}
+
+// runAndReport runs a download, printing progress and, if it's cancelled,
+// which steps finished. Every run is recorded in the history, whatever
+// the outcome. video is whatever's already known about the video, for the
+// history; it may be nil.
+func runAndReport(ctx context.Context, url string, video *videoInfo, opts DownloadOptions) error {
+	fmt.Fprintf(os.Stderr, "\nDownloading %s from:\n%s\n\n", opts, url)
+	return runPending(ctx, newPendingRun(url, video, opts))
+}
+
+// runPending runs what's left of p, saving its progress as it goes so it
+// can be resumed if tuber dies or is shut down part way through. Once it
+// ends any other way, it's recorded in the history and forgotten.
+func runPending(ctx context.Context, p *pendingRun) error {
+	p.PID = os.Getpid()
+	if err := p.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: couldn't save the run for resuming: %v\n", err)
+	}
+	ctx = withStepDone(ctx, p.stepDone)
+
+	res, err := runDownload(ctx, p.URL, p.Options, p.done(), nil)
+	if errors.Is(err, context.Canceled) && shuttingDown(ctx) {
+		fmt.Fprintf(os.Stderr, "\n✗ Stopped; run 'tuber resume' to finish (%s)\n", strings.Join(p.remaining(), ", "))
+		return err
+	}
+	p.remove()
+	video := &videoInfo{Title: p.Title, Channel: p.Channel, Duration: p.Duration}
+	recordRun(p.Started, p.URL, video, p.Options, res, err)
+
+	if errors.Is(err, context.Canceled) {
+		printAbortReport(p.Steps, res.Completed)
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "\n✓ Done!")
+	return nil
+}
+
+// printAbortReport lists which steps finished and which were cut short.
+func printAbortReport(planned, completed []string) {
+	done := make(map[string]bool)
+	for _, step := range completed {
+		done[step] = true
+	}
+	var aborted []string
+	for _, step := range planned {
+		if !done[step] {
+			aborted = append(aborted, step)
+		}
+	}
+
+	fmt.Fprintln(os.Stderr, "\n✗ Cancelled")
+	if len(completed) > 0 {
+		fmt.Fprintf(os.Stderr, "  completed: %s\n", strings.Join(completed, ", "))
+	}
+	if len(aborted) > 0 {
+		fmt.Fprintf(os.Stderr, "  aborted:   %s\n", strings.Join(aborted, ", "))
+	}
+	if keepPartial {
+		fmt.Fprintln(os.Stderr, "  partial files kept")
+	}
+}
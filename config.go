@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Config holds user defaults, read from config.json in the tuber config
+// directory. Command-line flags override anything set here.
+type Config struct {
+	OutputDir   string            `json:"output_dir,omitempty"`
+	Prompt      string            `json:"prompt,omitempty"`
+	Presets     map[string]string `json:"presets,omitempty"` // name -> summary prompt
+	LLM         LLMConfig         `json:"llm,omitzero"`
+	Attempts    int               `json:"attempts,omitempty"`
+	KeepPartial bool              `json:"keep_partial,omitempty"`
+	Embed       EmbedOptions      `json:"embed,omitzero"` // what to embed in downloaded files by default
+	Transcriber TranscriberConfig `json:"transcriber,omitzero"`
+	RateLimit   RateLimitConfig   `json:"rate_limit,omitzero"`
+	Auth        AuthConfig        `json:"auth,omitzero"`
+	Network     NetworkConfig     `json:"network,omitzero"`
+	YtdlpArgs   YtdlpArgsConfig   `json:"ytdlp_args,omitzero"` // extra yt-dlp arguments, for every run or by step
+}
+
+var cfg Config
+
+// configDir returns the directory tuber keeps its config and state in.
+func configDir() (string, error) {
+	if dir := os.Getenv("TUBER_HOME"); dir != "" {
+		return dir, nil
+	}
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(base, "tuber"), nil
+}
+
+func configPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// loadConfig reads the config file. A missing file is not an error.
+func loadConfig() (Config, error) {
+	var c Config
+	path, err := configPath()
+	if err != nil {
+		return c, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return c, err
+	}
+	if err := decodeConfig(data, &c); err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+func saveConfig(c Config) error {
+	path, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func decodeConfig(data []byte, c *Config) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil {
+		return err
+	}
+	if err := c.RateLimit.check(); err != nil {
+		return err
+	}
+	if err := c.Network.check(); err != nil {
+		return err
+	}
+	return c.YtdlpArgs.check()
+}
+
+// prompt returns the configured default summary prompt.
+func (c Config) prompt() string {
+	if c.Prompt != "" {
+		return c.Prompt
+	}
+	return defaultPrompt
+}
+
+// builtinPresets are the prompt presets available out of the box. Presets
+// in the config add to these, or replace them by using the same name.
+var builtinPresets = map[string]string{
+	"tldr": "Give a TL;DR of this YouTube video transcript in two or three sentences.",
+	"exercises": "This is a transcript of a workout video. List every exercise mentioned, " +
+		"with sets, reps or duration where given, as a numbered list. No commentary.",
+	"recipe": "This is a transcript of a cooking video. Write out the recipe: an ingredient list " +
+		"with quantities, then numbered steps. Skip the chit-chat.",
+	"meeting-notes": "Turn this transcript into meeting notes: a short summary, key decisions, " +
+		"and action items with owners where mentioned.",
+}
+
+// presets returns every available preset, builtin and configured.
+func (c Config) presets() map[string]string {
+	all := make(map[string]string)
+	for name, p := range builtinPresets {
+		all[name] = p
+	}
+	for name, p := range c.Presets {
+		all[name] = p
+	}
+	return all
+}
+
+// presetNames returns the available preset names, sorted.
+func (c Config) presetNames() []string {
+	var names []string
+	for name := range c.presets() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// preset looks up a prompt preset by name.
+func (c Config) preset(name string) (string, error) {
+	if p, ok := c.presets()[name]; ok {
+		return p, nil
+	}
+	return "", fmt.Errorf("no preset %q (have: %s)", name, strings.Join(c.presetNames(), ", "))
+}
+
+// configValue looks up a dotted key (e.g. "output_dir") in c.
+func configValue(c Config, key string) (any, bool) {
+	var v any = configMap(c)
+	for _, part := range strings.Split(key, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// setConfigValue sets a dotted key in c. The value is parsed as JSON when
+// it can be (numbers, booleans, lists), otherwise it's taken as a string.
+// A nil value removes the key.
+func setConfigValue(c Config, key string, value *string) (Config, error) {
+	root := configMap(c)
+	parts := strings.Split(key, ".")
+	m := root
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := m[part].(map[string]any)
+		if !ok {
+			next = make(map[string]any)
+			m[part] = next
+		}
+		m = next
+	}
+
+	last := parts[len(parts)-1]
+	if value == nil {
+		delete(m, last)
+		return configFromMap(root)
+	}
+
+	// Try the JSON reading first, then fall back to a plain string so
+	// e.g. an output_dir of "2024" still works
+	var parsed any
+	var jsonErr error
+	if err := json.Unmarshal([]byte(*value), &parsed); err == nil {
+		m[last] = parsed
+		updated, err := configFromMap(root)
+		if err == nil {
+			return updated, nil
+		}
+		jsonErr = err
+	}
+	m[last] = *value
+	updated, err := configFromMap(root)
+	if err != nil {
+		// What was wrong with the JSON reading says more, e.g. a list
+		// with a bad item rather than "not a list"
+		return c, fmt.Errorf("invalid value for %s: %w", key, cmp.Or(jsonErr, err))
+	}
+	return updated, nil
+}
+
+func configFromMap(m map[string]any) (Config, error) {
+	var c Config
+	data, err := json.Marshal(m)
+	if err != nil {
+		return c, err
+	}
+	err = decodeConfig(data, &c)
+	return c, err
+}
+
+// configMap converts c to a generic map keyed by JSON field names.
+func configMap(c Config) map[string]any {
+	data, _ := json.Marshal(c)
+	m := make(map[string]any)
+	json.Unmarshal(data, &m)
+	return m
+}
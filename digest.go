@@ -0,0 +1,265 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// A digestItem is one summarized video in a digest.
+type digestItem struct {
+	Title    string
+	URL      string
+	Channel  string
+	Duration float64
+	Time     time.Time
+	Summary  string
+}
+
+// A digestGroup is one channel's videos in a digest.
+type digestGroup struct {
+	Channel string
+	Items   []digestItem
+}
+
+// digestItems picks the summaries made since the given time out of the
+// history, keeping only the latest one for videos summarized more than
+// once. They come back oldest first.
+func digestItems(entries []historyEntry, since time.Time) []digestItem {
+	latest := make(map[string]int)
+	var items []digestItem
+	for _, e := range entries {
+		if e.Summary == "" || e.Outcome != outcomeDone || e.Time.Before(since) {
+			continue
+		}
+		item := digestItem{
+			Title:    cmp.Or(e.Title, e.URL),
+			URL:      e.URL,
+			Channel:  e.Channel,
+			Duration: e.Duration,
+			Time:     e.Time,
+			Summary:  strings.TrimSpace(e.Summary),
+		}
+		if i, ok := latest[e.URL]; ok {
+			items[i] = item
+			continue
+		}
+		latest[e.URL] = len(items)
+		items = append(items, item)
+	}
+	return items
+}
+
+// fillDigestInfo looks up the channel and duration of videos the history
+// doesn't have them for, which is anything not summarized by tuber watch
+// or from the menu. Lookups that fail are left blank.
+func fillDigestInfo(ctx context.Context, items []digestItem) {
+	var missing []int
+	for i, item := range items {
+		if item.Channel == "" {
+			missing = append(missing, i)
+		}
+	}
+	if len(missing) == 0 || requireYtdlp() != nil {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "🔎 Looking up %d videos...\n", len(missing))
+	for _, i := range missing {
+		info, err := fetchInfo(ctx, items[i].URL)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		items[i].Channel = info.Channel
+		if items[i].Duration == 0 {
+			items[i].Duration = info.Duration
+		}
+	}
+}
+
+// groupByChannel groups items by channel, channels in alphabetical order
+// with videos whose channel isn't known last.
+func groupByChannel(items []digestItem) []digestGroup {
+	var groups []digestGroup
+	for _, item := range items {
+		i := slices.IndexFunc(groups, func(g digestGroup) bool { return g.Channel == item.Channel })
+		if i < 0 {
+			groups = append(groups, digestGroup{Channel: item.Channel})
+			i = len(groups) - 1
+		}
+		groups[i].Items = append(groups[i].Items, item)
+	}
+	slices.SortStableFunc(groups, func(a, b digestGroup) int {
+		if (a.Channel == "") != (b.Channel == "") {
+			if a.Channel == "" {
+				return 1
+			}
+			return -1
+		}
+		return strings.Compare(strings.ToLower(a.Channel), strings.ToLower(b.Channel))
+	})
+	for i := range groups {
+		if groups[i].Channel == "" {
+			groups[i].Channel = "Other videos"
+		}
+	}
+	return groups
+}
+
+// digestHeading describes what a digest covers, e.g. "12 videos from 4
+// channels since Fri 17 Oct, 09:00".
+func digestHeading(groups []digestGroup, since time.Time) string {
+	videos := 0
+	for _, g := range groups {
+		videos += len(g.Items)
+	}
+	return fmt.Sprintf("%s from %s since %s",
+		plural(videos, "video"), plural(len(groups), "channel"), since.Local().Format("Mon 2 Jan, 15:04"))
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// writeMarkdownDigest writes the digest as Markdown. Summaries are
+// Markdown already, so they go in as they are.
+func writeMarkdownDigest(w io.Writer, groups []digestGroup, since, now time.Time) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Digest for %s\n\n_%s_\n", now.Local().Format("Monday 2 January 2006"), digestHeading(groups, since))
+	for _, g := range groups {
+		fmt.Fprintf(&b, "\n## %s\n", g.Channel)
+		for _, item := range g.Items {
+			fmt.Fprintf(&b, "\n### [%s](%s)", item.Title, item.URL)
+			if item.Duration > 0 {
+				fmt.Fprintf(&b, " (%s)", formatDuration(item.Duration))
+			}
+			fmt.Fprintf(&b, "\n\n%s\n", item.Summary)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+var digestPage = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"duration": formatDuration,
+	"markdown": markdownHTML,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font: 17px/1.55 Georgia, serif; max-width: 42rem; margin: 2rem auto; padding: 0 1rem; color: #222; background: #fdfcf8; }
+h1 { margin-bottom: 0; }
+.sub { color: #777; font-style: italic; margin-top: .25rem; }
+h2 { border-bottom: 1px solid #ddd; margin-top: 2.5rem; }
+h3 { font-size: 1.1rem; margin-bottom: .25rem; }
+h3 a { color: inherit; }
+.len { color: #888; font-weight: normal; font-size: .9rem; }
+a { color: #b0305c; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="sub">{{.Heading}}</p>
+{{range .Groups}}
+<h2>{{.Channel}}</h2>
+{{range .Items}}
+<h3><a href="{{.URL}}">{{.Title}}</a>{{if .Duration}} <span class="len">{{duration .Duration}}</span>{{end}}</h3>
+{{markdown .Summary}}
+{{end}}
+{{end}}
+</body>
+</html>
+`))
+
+// writeHTMLDigest writes the digest as a standalone HTML page.
+func writeHTMLDigest(w io.Writer, groups []digestGroup, since, now time.Time) error {
+	return digestPage.Execute(w, map[string]any{
+		"Title":   "Digest for " + now.Local().Format("Monday 2 January 2006"),
+		"Heading": digestHeading(groups, since),
+		"Groups":  groups,
+	})
+}
+
+var (
+	mdLink     = regexp.MustCompile(`\[([^\]]+)\]\((https?://[^)\s]+)\)`)
+	mdBold     = regexp.MustCompile(`\*\*(.+?)\*\*`)
+	mdCode     = regexp.MustCompile("`([^`]+)`")
+	mdBullet   = regexp.MustCompile(`^\s*[-*•]\s+`)
+	mdNumbered = regexp.MustCompile(`^\s*\d+[.)]\s+`)
+	mdHeading  = regexp.MustCompile(`^#{1,6}\s+`)
+)
+
+// markdownHTML renders the bits of Markdown summaries use (paragraphs,
+// lists, headings, links, bold and code) as HTML, escaping everything
+// else.
+func markdownHTML(md string) template.HTML {
+	var b strings.Builder
+	var para []string
+	list := "" // "ul" or "ol" while in a list
+
+	inline := func(s string) string {
+		s = html.EscapeString(strings.TrimSpace(s))
+		s = mdLink.ReplaceAllString(s, `<a href="$2">$1</a>`)
+		s = mdBold.ReplaceAllString(s, `<strong>$1</strong>`)
+		return mdCode.ReplaceAllString(s, `<code>$1</code>`)
+	}
+	flush := func() {
+		if len(para) > 0 {
+			b.WriteString("<p>" + inline(strings.Join(para, " ")) + "</p>\n")
+			para = nil
+		}
+		if list != "" {
+			b.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+
+	for _, line := range strings.Split(md, "\n") {
+		kind, text := "", line
+		switch {
+		case mdBullet.MatchString(line):
+			kind, text = "ul", mdBullet.ReplaceAllString(line, "")
+		case mdNumbered.MatchString(line):
+			kind, text = "ol", mdNumbered.ReplaceAllString(line, "")
+		}
+
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+		case kind != "":
+			if list != kind {
+				flush()
+				b.WriteString("<" + kind + ">\n")
+				list = kind
+			}
+			b.WriteString("<li>" + inline(text) + "</li>\n")
+		case mdHeading.MatchString(line):
+			flush()
+			b.WriteString("<h4>" + inline(mdHeading.ReplaceAllString(line, "")) + "</h4>\n")
+		default:
+			if list != "" {
+				flush()
+			}
+			para = append(para, line)
+		}
+	}
+	flush()
+	return template.HTML(b.String())
+}
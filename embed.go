@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EmbedOptions picks what yt-dlp writes into downloaded video and audio
+// files, so they describe themselves without the .txt and friends.
+type EmbedOptions struct {
+	Subs      bool `json:"subs,omitempty"`      // soft subtitles (video only)
+	Chapters  bool `json:"chapters,omitempty"`  // the video's own chapters
+	Thumbnail bool `json:"thumbnail,omitempty"` // as cover art
+	Metadata  bool `json:"metadata,omitempty"`  // title, channel, description, URL
+}
+
+// embedNames are the names -embed and the menu use, in display order.
+var embedNames = []string{"subs", "chapters", "thumbnail", "metadata"}
+
+func (e *EmbedOptions) field(name string) *bool {
+	switch name {
+	case "subs":
+		return &e.Subs
+	case "chapters":
+		return &e.Chapters
+	case "thumbnail":
+		return &e.Thumbnail
+	case "metadata":
+		return &e.Metadata
+	}
+	return nil
+}
+
+// String lists what's embedded, e.g. "thumbnail,metadata", in the form
+// parseEmbed reads.
+func (e EmbedOptions) String() string {
+	var parts []string
+	for _, name := range embedNames {
+		if *e.field(name) {
+			parts = append(parts, name)
+		}
+	}
+	if len(parts) == 0 {
+		return "none"
+	}
+	if len(parts) == len(embedNames) {
+		return "all"
+	}
+	return strings.Join(parts, ",")
+}
+
+// parseEmbed reads a comma-separated list of things to embed; "all" and
+// "none" do what they say.
+func parseEmbed(s string) (EmbedOptions, error) {
+	var e EmbedOptions
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch name {
+		case "", "none":
+			continue
+		case "all":
+			e = EmbedOptions{Subs: true, Chapters: true, Thumbnail: true, Metadata: true}
+			continue
+		}
+		f := e.field(name)
+		if f == nil {
+			return e, fmt.Errorf("can't embed %q: pick from %s, all or none", name, strings.Join(embedNames, ", "))
+		}
+		*f = true
+	}
+	return e, nil
+}
+
+// ytdlpArgs returns the yt-dlp flags that embed e. Audio files can't hold
+// subtitles, so they're only added to video.
+func (e EmbedOptions) ytdlpArgs(video bool) []string {
+	var args []string
+	if e.Subs && video {
+		args = append(args, "--embed-subs", "--write-subs", "--write-auto-subs", "--sub-langs", "en")
+	}
+	if e.Chapters {
+		args = append(args, "--embed-chapters")
+	}
+	if e.Thumbnail {
+		args = append(args, "--embed-thumbnail")
+	}
+	if e.Metadata {
+		// Title, channel (as artist), upload date, description and the
+		// video's URL (as purl/comment)
+		args = append(args, "--embed-metadata")
+	}
+	return args
+}
+
+// embedFlag is a flag.Value that parses -embed into an EmbedOptions.
+type embedFlag struct{ opts *EmbedOptions }
+
+func (f embedFlag) String() string {
+	if f.opts == nil {
+		return ""
+	}
+	return f.opts.String()
+}
+
+func (f embedFlag) Set(s string) error {
+	e, err := parseEmbed(s)
+	if err != nil {
+		return err
+	}
+	*f.opts = e
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestParseEmbed(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    EmbedOptions
+		wantErr bool
+	}{
+		{in: "", want: EmbedOptions{}},
+		{in: "none", want: EmbedOptions{}},
+		{in: "all", want: EmbedOptions{Subs: true, Chapters: true, Thumbnail: true, Metadata: true}},
+		{in: "Thumbnail, metadata", want: EmbedOptions{Thumbnail: true, Metadata: true}},
+		{in: "all,none", want: EmbedOptions{Subs: true, Chapters: true, Thumbnail: true, Metadata: true}},
+		{in: "subs,lyrics", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseEmbed(tt.in)
+		if (err != nil) != tt.wantErr || (!tt.wantErr && got != tt.want) {
+			t.Errorf("parseEmbed(%q) = %+v, %v, want %+v", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestEmbedOptionsRoundTrip(t *testing.T) {
+	for _, e := range []EmbedOptions{
+		{},
+		{Chapters: true},
+		{Thumbnail: true, Metadata: true},
+		{Subs: true, Chapters: true, Thumbnail: true, Metadata: true},
+	} {
+		got, err := parseEmbed(e.String())
+		if err != nil || got != e {
+			t.Errorf("parseEmbed(%q) = %+v, %v, want %+v", e.String(), got, err, e)
+		}
+	}
+}
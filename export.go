@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// A corpusEntry is one video's line in an export's corpus.jsonl.
+type corpusEntry struct {
+	ID       string  `json:"id"`
+	URL      string  `json:"url"`
+	Title    string  `json:"title"`
+	Channel  string  `json:"channel,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+	File     string  `json:"file"` // the per-video transcript, relative to the corpus
+	Text     string  `json:"text"`
+	Cues     []cue   `json:"cues"`
+}
+
+// exportOptions control a transcript export.
+type exportOptions struct {
+	Dir     string
+	Limit   int // newest uploads to export, 0 for all
+	Workers int
+}
+
+// defaultExportRate is how many requests a minute an export makes when
+// there's no rate limit set up.
+const defaultExportRate = 30
+
+// exportStats count how an export went.
+type exportStats struct {
+	Exported, Skipped, Failed int
+}
+
+// exportTranscripts fetches the subtitles of every video in a channel or
+// playlist, without the media, writing each as <title> [<id>].txt and
+// all of them to corpus.jsonl in opts.Dir. Videos already in the corpus
+// are skipped, so an export can be run again to pick up new uploads or
+// finish one that was interrupted.
+func exportTranscripts(ctx context.Context, rawURL string, opts exportOptions) (exportStats, error) {
+	var stats exportStats
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return stats, err
+	}
+	corpusPath := filepath.Join(opts.Dir, "corpus.jsonl")
+	done, err := corpusIDs(corpusPath)
+	if err != nil {
+		return stats, err
+	}
+
+	fmt.Fprintf(os.Stderr, "📋 Listing %s...\n", rawURL)
+	uploads, err := listUploads(ctx, rawURL, opts.Limit)
+	if err != nil {
+		return stats, err
+	}
+	var todo []upload
+	for _, u := range uploads {
+		if _, id, _ := strings.Cut(u.Key, " "); done[id] {
+			stats.Skipped++
+			continue
+		}
+		todo = append(todo, u)
+	}
+	fmt.Fprintf(os.Stderr, "📋 %s, %d already exported\n", plural(len(uploads), "video"), stats.Skipped)
+	if len(todo) == 0 {
+		return stats, nil
+	}
+
+	corpus, err := os.OpenFile(corpusPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return stats, err
+	}
+	defer corpus.Close()
+
+	var (
+		mu       sync.Mutex
+		writeErr error
+		n        int
+	)
+	jobs := make(chan upload)
+	var wg sync.WaitGroup
+	for range max(opts.Workers, 1) {
+		wg.Go(func() {
+			for u := range jobs {
+				entry, err := exportVideo(ctx, u, opts.Dir)
+
+				mu.Lock()
+				n++
+				switch {
+				case ctx.Err() != nil:
+				case err != nil:
+					stats.Failed++
+					fmt.Fprintf(os.Stderr, "✗ [%d/%d] %s: %v\n", n, len(todo), u.Title, err)
+				default:
+					if err := json.NewEncoder(corpus).Encode(entry); err != nil && writeErr == nil {
+						writeErr = err
+					}
+					stats.Exported++
+					fmt.Fprintf(os.Stderr, "✓ [%d/%d] %s\n", n, len(todo), u.Title)
+				}
+				mu.Unlock()
+			}
+		})
+	}
+
+feed:
+	for i, u := range todo {
+		// Fetches are paced by the rate limit; this is any extra pause
+		if i > 0 && pause(ctx, u.URL) != nil {
+			break feed
+		}
+		select {
+		case <-ctx.Done():
+			break feed
+		case jobs <- u:
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if writeErr != nil {
+		return stats, writeErr
+	}
+	return stats, ctx.Err()
+}
+
+// exportVideo fetches one video's subtitles and writes its transcript
+// file, returning its corpus entry.
+func exportVideo(ctx context.Context, u upload, dir string) (corpusEntry, error) {
+	_, id, _ := strings.Cut(u.Key, " ")
+	_, cues, err := fetchCues(ctx, u.URL, io.Discard)
+	if err != nil {
+		return corpusEntry{}, err
+	}
+	lines := make([]string, len(cues))
+	for i, c := range cues {
+		lines[i] = c.Text
+	}
+	text := strings.Join(lines, "\n")
+
+	name := fmt.Sprintf("%s [%s].txt", sanitizeFilename(u.Title), id)
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(text), 0644); err != nil {
+		return corpusEntry{}, err
+	}
+	indexTranscript(u.URL, path, cues)
+
+	return corpusEntry{
+		ID:       id,
+		URL:      u.URL,
+		Title:    u.Title,
+		Channel:  u.Channel,
+		Duration: u.Duration,
+		File:     name,
+		Text:     text,
+		Cues:     cues,
+	}, nil
+}
+
+// corpusIDs returns the IDs of the videos already in a corpus file.
+func corpusIDs(path string) (map[string]bool, error) {
+	ids := make(map[string]bool)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ids, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	// Each line holds a whole transcript
+	scanner.Buffer(nil, 64<<20)
+	for scanner.Scan() {
+		var entry struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry.ID != "" {
+			ids[entry.ID] = true
+		}
+	}
+	return ids, scanner.Err()
+}
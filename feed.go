@@ -0,0 +1,328 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// feedAudio are the extensions that go in a podcast feed.
+var feedAudio = map[string]string{".mp3": "audio/mpeg", ".m4a": "audio/mp4", ".opus": "audio/ogg"}
+
+// feedArtwork are the filenames checked for the podcast's own artwork.
+var feedArtwork = []string{"cover.jpg", "cover.png", "folder.jpg", "folder.png"}
+
+// A feedEpisode is one audio file in a podcast feed.
+type feedEpisode struct {
+	File     string // name within the feed's directory
+	Size     int64
+	Title    string
+	Link     string // the video it came from, if known
+	Author   string
+	Summary  string
+	Duration float64 // seconds, 0 if unknown
+	Time     time.Time
+	Image    string // name of a thumbnail next to the file, if there is one
+}
+
+// feedEpisodes finds the audio files in dir, newest first, filling in
+// what the history knows about each.
+func feedEpisodes(dir string) ([]feedEpisode, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	history, err := loadHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	// History paths are relative to wherever tuber was run, so match on
+	// the filename; later runs win
+	runs := make(map[string]historyEntry)
+	for _, e := range history {
+		for _, f := range e.Files {
+			runs[filepath.Base(f)] = e
+		}
+	}
+	names := make(map[string]bool)
+	for _, entry := range entries {
+		names[entry.Name()] = true
+	}
+
+	var episodes []feedEpisode
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || feedAudio[ext] == "" || partialFile.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		ep := feedEpisode{
+			File:  entry.Name(),
+			Size:  info.Size(),
+			Title: strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())),
+			Time:  info.ModTime(),
+		}
+		if run, ok := runs[entry.Name()]; ok {
+			ep.Title = cmp.Or(run.Title, ep.Title)
+			ep.Link = run.URL
+			ep.Author = run.Channel
+			ep.Summary = strings.TrimSpace(run.Summary)
+			ep.Duration = run.Duration
+			ep.Time = run.Time
+		}
+		if ep.Duration == 0 {
+			ep.Duration = probeDuration(filepath.Join(dir, entry.Name()))
+		}
+		base := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		for _, ext := range []string{".jpg", ".png", ".webp"} {
+			if names[base+ext] {
+				ep.Image = base + ext
+				break
+			}
+		}
+		episodes = append(episodes, ep)
+	}
+
+	slices.SortFunc(episodes, func(a, b feedEpisode) int { return b.Time.Compare(a.Time) })
+	return episodes, nil
+}
+
+// probeDuration asks ffprobe how long an audio file is, returning 0 if it
+// can't say.
+func probeDuration(path string) float64 {
+	out, err := exec.Command("ffprobe", "-v", "error", "-show_entries", "format=duration", "-of", "csv=p=0", path).Output()
+	if err != nil {
+		return 0
+	}
+	d, _ := strconv.ParseFloat(strings.TrimSpace(string(out)), 64)
+	return d
+}
+
+// The RSS 2.0 document, with the iTunes tags podcast apps look for.
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	ITunes  string     `xml:"xmlns:itunes,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Language    string    `xml:"language"`
+	Generator   string    `xml:"generator"`
+	Image       *rssImage `xml:"itunes:image,omitempty"`
+	Author      string    `xml:"itunes:author,omitempty"`
+	Explicit    string    `xml:"itunes:explicit"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssImage struct {
+	Href string `xml:"href,attr"`
+}
+
+type rssItem struct {
+	Title       string       `xml:"title"`
+	Link        string       `xml:"link,omitempty"`
+	Description string       `xml:"description,omitempty"`
+	GUID        rssGUID      `xml:"guid"`
+	PubDate     string       `xml:"pubDate"`
+	Enclosure   rssEnclosure `xml:"enclosure"`
+	Duration    string       `xml:"itunes:duration,omitempty"`
+	Author      string       `xml:"itunes:author,omitempty"`
+	Image       *rssImage    `xml:"itunes:image,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Length int64  `xml:"length,attr"`
+	Type   string `xml:"type,attr"`
+}
+
+// feedOptions describe the podcast as a whole.
+type feedOptions struct {
+	Title   string
+	BaseURL string // where dir is served from, ending in /
+	Image   string // artwork: a URL, or a file in dir
+}
+
+// writeFeed writes a podcast RSS feed of episodes to w. Enclosure and
+// artwork URLs are made absolute against opts.BaseURL, as podcast apps
+// need.
+func writeFeed(w io.Writer, episodes []feedEpisode, opts feedOptions) error {
+	fileURL := func(name string) string {
+		return opts.BaseURL + (&url.URL{Path: name}).EscapedPath()
+	}
+
+	ch := rssChannel{
+		Title:       opts.Title,
+		Link:        opts.BaseURL,
+		Description: "Videos downloaded with tuber",
+		Language:    "en",
+		Generator:   "tuber",
+		Explicit:    "false",
+	}
+	if opts.Image != "" {
+		href := opts.Image
+		if !strings.Contains(href, "://") {
+			href = fileURL(href)
+		}
+		ch.Image = &rssImage{Href: href}
+	}
+
+	for _, ep := range episodes {
+		item := rssItem{
+			Title:       ep.Title,
+			Link:        ep.Link,
+			Description: string(markdownHTML(ep.showNotes())),
+			GUID:        rssGUID{Value: cmp.Or(ep.Link, fileURL(ep.File))},
+			PubDate:     ep.Time.UTC().Format(time.RFC1123Z),
+			Enclosure: rssEnclosure{
+				URL:    fileURL(ep.File),
+				Length: ep.Size,
+				Type:   feedAudio[strings.ToLower(filepath.Ext(ep.File))],
+			},
+			Author: ep.Author,
+		}
+		if ep.Duration > 0 {
+			item.Duration = formatDuration(ep.Duration)
+		}
+		if ep.Image != "" {
+			item.Image = &rssImage{Href: fileURL(ep.Image)}
+		}
+		ch.Items = append(ch.Items, item)
+	}
+
+	feed := rssFeed{Version: "2.0", ITunes: "http://www.itunes.com/dtds/podcast-1.0.dtd", Channel: ch}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(feed); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// showNotes are the episode's description, in Markdown: its summary, and
+// where it came from. Podcast apps take HTML, so it's rendered before it
+// goes in the feed.
+func (ep feedEpisode) showNotes() string {
+	var parts []string
+	if ep.Summary != "" {
+		parts = append(parts, ep.Summary)
+	}
+	if ep.Link != "" {
+		parts = append(parts, "[Original video]("+ep.Link+")")
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// feedImage returns the artwork file in dir, if there is one.
+func feedImage(dir string) string {
+	for _, name := range feedArtwork {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// serveFeed serves dir over HTTP, with the feed at /feed.xml built fresh
+// for each request so new downloads show up straight away. Enclosure URLs
+// use whatever host the request came in on, so the feed works from any
+// machine on the network.
+func serveFeed(ctx context.Context, addr, dir string, opts feedOptions) error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /feed.xml", func(w http.ResponseWriter, r *http.Request) {
+		episodes, err := feedEpisodes(dir)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		reqOpts := opts
+		if reqOpts.BaseURL == "" {
+			reqOpts.BaseURL = "http://" + r.Host + "/"
+		}
+		w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+		writeFeed(w, episodes, reqOpts)
+	})
+	mux.Handle("GET /", http.FileServer(http.Dir(dir)))
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintf(os.Stderr, "📻 Serving %s\n", dir)
+	for _, host := range lanHosts(ln.Addr()) {
+		fmt.Fprintf(os.Stderr, "   http://%s/feed.xml\n", host)
+	}
+	fmt.Fprintln(os.Stderr, "Press ctrl+c to stop")
+
+	if err := srv.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return ctx.Err()
+}
+
+// lanHosts lists host:port pairs other machines could reach addr on.
+func lanHosts(addr net.Addr) []string {
+	tcp, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return []string{addr.String()}
+	}
+	port := strconv.Itoa(tcp.Port)
+	if !tcp.IP.IsUnspecified() {
+		return []string{net.JoinHostPort(tcp.IP.String(), port)}
+	}
+
+	hosts := []string{net.JoinHostPort("localhost", port)}
+	addrs, _ := net.InterfaceAddrs()
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLoopback() && ipnet.IP.To4() != nil {
+			hosts = append(hosts, net.JoinHostPort(ipnet.IP.String(), port))
+		}
+	}
+	return hosts
+}
+
+func init() {
+	// Not every system's mime table knows these, and podcast apps care
+	for ext, typ := range feedAudio {
+		mime.AddExtensionType(ext, typ)
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A historyEntry records one run.
+type historyEntry struct {
+	ID        int             `json:"id"`
+	Time      time.Time       `json:"time"`
+	URL       string          `json:"url"`
+	Title     string          `json:"title,omitempty"`
+	Channel   string          `json:"channel,omitempty"`
+	Duration  float64         `json:"duration,omitempty"` // seconds
+	Options   DownloadOptions `json:"options"`
+	OutputDir string          `json:"output_dir,omitempty"`
+	OutPath   string          `json:"out_path,omitempty"` // output path picked in the menu
+	Files     []string        `json:"files,omitempty"`
+	Outcome   string          `json:"outcome"` // "done", "cancelled" or "failed"
+	Error     string          `json:"error,omitempty"`
+	Summary   string          `json:"summary,omitempty"`
+}
+
+const (
+	outcomeDone      = "done"
+	outcomeCancelled = "cancelled"
+	outcomeFailed    = "failed"
+)
+
+// historyMu serializes appends; IDs come from the line count.
+var historyMu sync.Mutex
+
+func historyPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "history.jsonl"), nil
+}
+
+// loadHistory returns every recorded run, oldest first.
+func loadHistory() ([]historyEntry, error) {
+	path, err := historyPath()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []historyEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e historyEntry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			// Skip anything half-written by a crash
+			continue
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// appendHistory adds e to the history file, assigning its ID.
+func appendHistory(e historyEntry) (historyEntry, error) {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+
+	entries, err := loadHistory()
+	if err != nil {
+		return e, err
+	}
+	e.ID = 1
+	if len(entries) > 0 {
+		e.ID = entries[len(entries)-1].ID + 1
+	}
+
+	path, err := historyPath()
+	if err != nil {
+		return e, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return e, err
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return e, err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return e, err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return e, err
+}
+
+// findHistory returns the entry with the given ID.
+func findHistory(id int) (historyEntry, bool, error) {
+	entries, err := loadHistory()
+	if err != nil {
+		return historyEntry{}, false, err
+	}
+	for _, e := range entries {
+		if e.ID == id {
+			return e, true, nil
+		}
+	}
+	return historyEntry{}, false, nil
+}
+
+// recentRuns returns the latest run for each of up to n distinct URLs,
+// newest first.
+func recentRuns(n int) []historyEntry {
+	entries, err := loadHistory()
+	if err != nil {
+		return nil
+	}
+	var recent []historyEntry
+	seen := make(map[string]bool)
+	for i := len(entries) - 1; i >= 0 && len(recent) < n; i-- {
+		if !seen[entries[i].URL] {
+			seen[entries[i].URL] = true
+			recent = append(recent, entries[i])
+		}
+	}
+	return recent
+}
+
+// matches reports whether e's title or URL contains query, ignoring case.
+func (e historyEntry) matches(query string) bool {
+	query = strings.ToLower(query)
+	return strings.Contains(strings.ToLower(e.Title), query) ||
+		strings.Contains(strings.ToLower(e.URL), query)
+}
+
+// titleFromFiles guesses a title from output files named %(title)s.ext.
+func titleFromFiles(files []string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	base := filepath.Base(files[0])
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// recordRun adds a run to the history, whatever its outcome. video is
+// whatever's known about the video; it may be nil. Failing to record is
+// only worth a warning.
+func recordRun(started time.Time, url string, video *videoInfo, opts DownloadOptions, res runResult, err error) historyEntry {
+	entry := historyEntry{
+		Time:      started,
+		URL:       url,
+		Options:   opts,
+		OutputDir: outputDir,
+		OutPath:   customOutPath,
+		Files:     res.Files,
+		Outcome:   outcomeDone,
+		Summary:   res.Summary,
+	}
+	if video != nil {
+		entry.Title = video.Title
+		entry.Channel = video.Channel
+		entry.Duration = video.Duration
+	}
+	if entry.Title == "" {
+		entry.Title = titleFromFiles(res.Files)
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		entry.Outcome = outcomeCancelled
+	case err != nil:
+		entry.Outcome = outcomeFailed
+		entry.Error = err.Error()
+	}
+	entry, herr := appendHistory(entry)
+	if herr != nil {
+		fmt.Fprintf(os.Stderr, "Warning: couldn't record history: %v\n", herr)
+	}
+	return entry
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// videoInfo is the normalized metadata for a single video.
+type videoInfo struct {
+	ID           string           `json:"id"`
+	URL          string           `json:"url"`
+	Title        string           `json:"title"`
+	Channel      string           `json:"channel"`
+	ChannelURL   string           `json:"channel_url,omitempty"`
+	UploadDate   string           `json:"upload_date,omitempty"` // YYYY-MM-DD
+	Duration     float64          `json:"duration"`              // seconds
+	Description  string           `json:"description,omitempty"`
+	Chapters     []videoChapter   `json:"chapters"`
+	Formats      []videoFormat    `json:"formats"`
+	Subtitles    []string         `json:"subtitles"`     // languages with uploaded subtitles
+	AutoCaptions []string         `json:"auto_captions"` // languages with automatic captions
+	Thumbnails   []videoThumbnail `json:"thumbnails"`
+
+	raw []byte // yt-dlp's JSON, as fetched
+}
+
+type videoChapter struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Title string  `json:"title"`
+}
+
+type videoFormat struct {
+	ID         string `json:"id"`
+	Ext        string `json:"ext"`
+	Resolution string `json:"resolution"`
+	VideoCodec string `json:"video_codec,omitempty"`
+	AudioCodec string `json:"audio_codec,omitempty"`
+	Size       int64  `json:"size,omitempty"` // bytes, possibly estimated
+}
+
+type videoThumbnail struct {
+	URL    string `json:"url"`
+	Width  int    `json:"width,omitempty"`
+	Height int    `json:"height,omitempty"`
+}
+
+// ytdlpInfo mirrors the parts of yt-dlp's --dump-json output we use.
+type ytdlpInfo struct {
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	Channel     string  `json:"channel"`
+	Uploader    string  `json:"uploader"`
+	ChannelURL  string  `json:"channel_url"`
+	WebpageURL  string  `json:"webpage_url"`
+	UploadDate  string  `json:"upload_date"`
+	Duration    float64 `json:"duration"`
+	Description string  `json:"description"`
+	Chapters    []struct {
+		StartTime float64 `json:"start_time"`
+		EndTime   float64 `json:"end_time"`
+		Title     string  `json:"title"`
+	} `json:"chapters"`
+	Formats []struct {
+		FormatID       string `json:"format_id"`
+		FormatNote     string `json:"format_note"`
+		Ext            string `json:"ext"`
+		Resolution     string `json:"resolution"`
+		VCodec         string `json:"vcodec"`
+		ACodec         string `json:"acodec"`
+		Filesize       int64  `json:"filesize"`
+		FilesizeApprox int64  `json:"filesize_approx"`
+	} `json:"formats"`
+	Subtitles         map[string]json.RawMessage `json:"subtitles"`
+	AutomaticCaptions map[string]json.RawMessage `json:"automatic_captions"`
+	Thumbnails        []struct {
+		URL    string `json:"url"`
+		Width  int    `json:"width"`
+		Height int    `json:"height"`
+	} `json:"thumbnails"`
+}
+
+// infoCacheTTL is how long fetched metadata is reused for. The format
+// URLs in it expire after a few hours, so it's kept well short of that.
+const infoCacheTTL = 10 * time.Minute
+
+// infoCache holds recently fetched metadata, so the menu's preview, the
+// download and its chapters all share one fetch, and the files runs hand
+// it to yt-dlp in.
+var infoCache = struct {
+	sync.Mutex
+	fetched map[string]cachedInfo
+	files   map[string]*sharedInfo // by URL, while runs are using them
+}{fetched: make(map[string]cachedInfo), files: make(map[string]*sharedInfo)}
+
+// A sharedInfo is a --load-info-json file, and how many runs are using
+// it; it's removed when the last one is done.
+type sharedInfo struct {
+	path  string
+	users int
+}
+
+type cachedInfo struct {
+	info *videoInfo
+	at   time.Time
+}
+
+// cachedVideoInfo returns url's metadata if it was fetched recently.
+func cachedVideoInfo(url string) *videoInfo {
+	infoCache.Lock()
+	defer infoCache.Unlock()
+	if c, ok := infoCache.fetched[url]; ok && time.Since(c.at) < infoCacheTTL {
+		return c.info
+	}
+	return nil
+}
+
+// fetchInfo asks yt-dlp for a video's metadata without downloading it,
+// unless it already has recently.
+func fetchInfo(ctx context.Context, url string) (*videoInfo, error) {
+	if info := cachedVideoInfo(url); info != nil {
+		return info, nil
+	}
+	args := []string{"--dump-json", "--no-playlist", "--no-warnings"}
+	args = append(args, cfg.YtdlpArgs.forStep("info")...)
+	args = append(args, url)
+	var out []byte
+	err := withRetry(ctx, func() error {
+		var err error
+		out, err = ytdlpOutput(ctx, url, args...)
+		return err
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	info, err := parseInfo(out)
+	if err != nil {
+		return nil, err
+	}
+
+	infoCache.Lock()
+	defer infoCache.Unlock()
+	now := time.Now()
+	for u, c := range infoCache.fetched {
+		if now.Sub(c.at) >= infoCacheTTL {
+			delete(infoCache.fetched, u)
+		}
+	}
+	infoCache.fetched[url] = cachedInfo{info: info, at: now}
+	return info, nil
+}
+
+// shareInfo fetches url's metadata, if it hasn't been already, and saves
+// it for the yt-dlp runs that follow, which load it with --load-info-json
+// (see ytdlpSource) rather than each extracting the page again. That's
+// quicker, and means every step agrees on the title and so the filename.
+// Runs of the same URL at once share one file. The returned func is this
+// run done with it.
+func shareInfo(ctx context.Context, url string) (func(), error) {
+	infoCache.Lock()
+	if shared, ok := infoCache.files[url]; ok {
+		shared.users++
+		infoCache.Unlock()
+		return func() { releaseInfo(url) }, nil
+	}
+	infoCache.Unlock()
+
+	info, err := fetchInfo(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.CreateTemp("", "tuber-info-*.json")
+	if err != nil {
+		return nil, err
+	}
+	_, err = f.Write(info.raw)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	infoCache.Lock()
+	defer infoCache.Unlock()
+	if shared, ok := infoCache.files[url]; ok {
+		// Another run shared it while this one was fetching
+		os.Remove(f.Name())
+		shared.users++
+	} else {
+		infoCache.files[url] = &sharedInfo{path: f.Name(), users: 1}
+	}
+	return func() { releaseInfo(url) }, nil
+}
+
+// releaseInfo is a run done with url's shared metadata.
+func releaseInfo(url string) {
+	infoCache.Lock()
+	defer infoCache.Unlock()
+	shared := infoCache.files[url]
+	if shared.users--; shared.users == 0 {
+		delete(infoCache.files, url)
+		os.Remove(shared.path)
+	}
+}
+
+// ytdlpSource returns the yt-dlp args that say which video to work on:
+// its shared metadata if there is some, otherwise its URL.
+func ytdlpSource(url string) []string {
+	infoCache.Lock()
+	defer infoCache.Unlock()
+	if shared, ok := infoCache.files[url]; ok {
+		return []string{"--load-info-json", shared.path}
+	}
+	return []string{url}
+}
+
+func parseInfo(data []byte) (*videoInfo, error) {
+	var raw ytdlpInfo
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("reading yt-dlp metadata: %w", err)
+	}
+
+	info := &videoInfo{
+		ID:          raw.ID,
+		URL:         raw.WebpageURL,
+		Title:       raw.Title,
+		Channel:     raw.Channel,
+		ChannelURL:  raw.ChannelURL,
+		Duration:    raw.Duration,
+		Description: raw.Description,
+		raw:         data,
+	}
+	if info.Channel == "" {
+		info.Channel = raw.Uploader
+	}
+	if d := raw.UploadDate; len(d) == 8 {
+		info.UploadDate = d[:4] + "-" + d[4:6] + "-" + d[6:]
+	}
+
+	for _, c := range raw.Chapters {
+		info.Chapters = append(info.Chapters, videoChapter{Start: c.StartTime, End: c.EndTime, Title: c.Title})
+	}
+
+	for _, f := range raw.Formats {
+		// Storyboards are preview image grids, not something you'd download
+		if f.Ext == "mhtml" || strings.Contains(f.FormatNote, "storyboard") {
+			continue
+		}
+		vf := videoFormat{
+			ID:         f.FormatID,
+			Ext:        f.Ext,
+			Resolution: f.Resolution,
+			Size:       f.Filesize,
+		}
+		if vf.Size == 0 {
+			vf.Size = f.FilesizeApprox
+		}
+		if f.VCodec != "none" {
+			vf.VideoCodec = f.VCodec
+		}
+		if f.ACodec != "none" {
+			vf.AudioCodec = f.ACodec
+		}
+		info.Formats = append(info.Formats, vf)
+	}
+
+	info.Subtitles = languages(raw.Subtitles)
+	info.AutoCaptions = languages(raw.AutomaticCaptions)
+
+	for _, t := range raw.Thumbnails {
+		info.Thumbnails = append(info.Thumbnails, videoThumbnail{URL: t.URL, Width: t.Width, Height: t.Height})
+	}
+
+	return info, nil
+}
+
+// languages returns the sorted language codes in a yt-dlp subtitles map,
+// skipping live chat replays.
+func languages(subs map[string]json.RawMessage) []string {
+	langs := []string{}
+	for lang := range subs {
+		if lang != "live_chat" {
+			langs = append(langs, lang)
+		}
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// formatDuration renders seconds as m:ss or h:mm:ss.
+func formatDuration(seconds float64) string {
+	s := int(seconds)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// formatSize renders a byte count for humans.
+func formatSize(n int64) string {
+	if n <= 0 {
+		return "-"
+	}
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// printInfo writes a human-readable description of info to w.
+func printInfo(w io.Writer, info *videoInfo) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Title:\t%s\n", info.Title)
+	fmt.Fprintf(tw, "Channel:\t%s\n", info.Channel)
+	fmt.Fprintf(tw, "Duration:\t%s\n", formatDuration(info.Duration))
+	if info.UploadDate != "" {
+		fmt.Fprintf(tw, "Uploaded:\t%s\n", info.UploadDate)
+	}
+	fmt.Fprintf(tw, "URL:\t%s\n", info.URL)
+	tw.Flush()
+
+	if len(info.Chapters) > 0 {
+		fmt.Fprintln(w, "\nChapters:")
+		for _, c := range info.Chapters {
+			fmt.Fprintf(w, "  %8s  %s\n", formatDuration(c.Start), c.Title)
+		}
+	}
+
+	if len(info.Formats) > 0 {
+		fmt.Fprintln(w, "\nFormats:")
+		tw = tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  ID\tEXT\tRESOLUTION\tVIDEO\tAUDIO\tSIZE")
+		for _, f := range info.Formats {
+			fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n",
+				f.ID, f.Ext, f.Resolution, orDash(f.VideoCodec), orDash(f.AudioCodec), formatSize(f.Size))
+		}
+		tw.Flush()
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Subtitles:      %s\n", languageList(info.Subtitles))
+	fmt.Fprintf(w, "Auto captions:  %s\n", languageList(info.AutoCaptions))
+
+	if len(info.Thumbnails) > 0 {
+		fmt.Fprintln(w, "\nThumbnails:")
+		for _, t := range info.Thumbnails {
+			size := "?"
+			if t.Width > 0 && t.Height > 0 {
+				size = fmt.Sprintf("%dx%d", t.Width, t.Height)
+			}
+			fmt.Fprintf(w, "  %-9s  %s\n", size, t.URL)
+		}
+	}
+}
+
+// languageList shortens long language lists; auto captions usually come
+// machine-translated into 100+ languages.
+func languageList(langs []string) string {
+	const max = 10
+	switch {
+	case len(langs) == 0:
+		return "none"
+	case len(langs) > max:
+		return fmt.Sprintf("%s, +%d more", strings.Join(langs[:max], ", "), len(langs)-max)
+	}
+	return strings.Join(langs, ", ")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
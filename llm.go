@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// LLMConfig picks the backend used for summaries and questions.
+type LLMConfig struct {
+	Backend   string `json:"backend,omitempty"`     // "claude" (default) or "openai"
+	Model     string `json:"model,omitempty"`       // optional for claude, required for openai
+	BaseURL   string `json:"base_url,omitempty"`    // openai: any compatible API, e.g. a local Ollama
+	APIKeyEnv string `json:"api_key_env,omitempty"` // openai: env var holding the key
+}
+
+const (
+	defaultOpenAIURL    = "https://api.openai.com/v1"
+	defaultOpenAIKeyEnv = "OPENAI_API_KEY"
+)
+
+// llmAvailable is set at startup if the configured backend looks usable;
+// llmErr says what's missing if not.
+var (
+	llmAvailable bool
+	llmErr       error
+)
+
+type chatMessage struct {
+	Role    string `json:"role"` // "user" or "assistant"
+	Content string `json:"content"`
+}
+
+// An llmRequest is a prompt about some context (usually a transcript),
+// optionally continuing a conversation.
+type llmRequest struct {
+	Prompt   string        // instructions
+	Context  string        // the material the prompt is about
+	Messages []chatMessage // conversation so far, ending with the user's turn
+}
+
+// An llmBackend answers requests, streaming the reply to w as it arrives
+// and returning it in full.
+type llmBackend interface {
+	complete(ctx context.Context, req llmRequest, w io.Writer) (string, error)
+}
+
+// checkLLM reports what, if anything, stops the configured backend working.
+// Messages are short enough to show next to the Summary option.
+func checkLLM(c LLMConfig) error {
+	switch c.Backend {
+	case "", "claude":
+		if _, err := exec.LookPath("claude"); err != nil {
+			return errors.New("install claude cli")
+		}
+	case "openai":
+		if c.Model == "" {
+			return errors.New("set llm.model in config")
+		}
+		keyEnv := c.APIKeyEnv
+		if keyEnv == "" {
+			keyEnv = defaultOpenAIKeyEnv
+		}
+		// Local servers don't need a key, the real API does
+		if (c.BaseURL == "" || c.BaseURL == defaultOpenAIURL) && os.Getenv(keyEnv) == "" {
+			return fmt.Errorf("set $%s", keyEnv)
+		}
+	default:
+		return fmt.Errorf("unknown llm.backend %q", c.Backend)
+	}
+	return nil
+}
+
+// newLLM returns the backend described by c.
+func newLLM(c LLMConfig) llmBackend {
+	if c.Backend == "openai" {
+		b := &openAIBackend{baseURL: c.BaseURL, model: c.Model, client: cfg.Network.httpClient(), userAgent: cfg.Network.UserAgent}
+		if b.baseURL == "" {
+			b.baseURL = defaultOpenAIURL
+		}
+		keyEnv := c.APIKeyEnv
+		if keyEnv == "" {
+			keyEnv = defaultOpenAIKeyEnv
+		}
+		b.apiKey = os.Getenv(keyEnv)
+		return b
+	}
+	return &claudeBackend{model: c.Model}
+}
+
+// claudeBackend pipes requests through the claude CLI.
+type claudeBackend struct {
+	model string
+}
+
+func (b *claudeBackend) complete(ctx context.Context, req llmRequest, w io.Writer) (string, error) {
+	args := []string{"-p", req.Prompt}
+	if b.model != "" {
+		args = append(args, "--model", b.model)
+	}
+
+	// The context goes on stdin rather than in -p: transcripts can be
+	// longer than the OS allows a single argument to be
+	input := req.Context
+	if len(req.Messages) > 0 {
+		input += "\n\n" + renderConversation(req.Messages)
+	}
+
+	var out bytes.Buffer
+	cmd := exec.CommandContext(ctx, "claude", args...)
+	setProcessGroup(cmd)
+	cmd.Env = append(os.Environ(), cfg.Network.env()...)
+	cmd.Stdin = strings.NewReader(input)
+	cmd.Stdout = io.MultiWriter(w, &out)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// renderConversation writes out chat turns for backends that only take
+// one block of text.
+func renderConversation(msgs []chatMessage) string {
+	var b strings.Builder
+	b.WriteString("Conversation so far:\n")
+	for _, m := range msgs {
+		who := "User"
+		if m.Role == "assistant" {
+			who = "Assistant"
+		}
+		fmt.Fprintf(&b, "\n%s: %s\n", who, m.Content)
+	}
+	b.WriteString("\nReply to the user's last message.")
+	return b.String()
+}
+
+// openAIBackend talks to an OpenAI-compatible chat completions API.
+type openAIBackend struct {
+	baseURL   string
+	model     string
+	apiKey    string
+	userAgent string
+	client    *http.Client
+}
+
+func (b *openAIBackend) complete(ctx context.Context, req llmRequest, w io.Writer) (string, error) {
+	msgs := []chatMessage{{Role: "system", Content: req.Prompt}}
+	if len(req.Messages) == 0 {
+		msgs = append(msgs, chatMessage{Role: "user", Content: req.Context})
+	} else {
+		// Fold the context into the first user turn
+		first := req.Messages[0]
+		first.Content = req.Context + "\n\n" + first.Content
+		msgs = append(msgs, first)
+		msgs = append(msgs, req.Messages[1:]...)
+	}
+
+	body, err := json.Marshal(map[string]any{"model": b.model, "messages": msgs})
+	if err != nil {
+		return "", err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(b.baseURL, "/")+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if b.apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+b.apiKey)
+	}
+	if b.userAgent != "" {
+		httpReq.Header.Set("User-Agent", b.userAgent)
+	}
+
+	resp, err := b.client.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Choices []struct {
+			Message chatMessage `json:"message"`
+		} `json:"choices"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("llm: %s: %w", resp.Status, err)
+	}
+	if result.Error != nil {
+		return "", fmt.Errorf("llm: %s", result.Error.Message)
+	}
+	if resp.StatusCode != http.StatusOK || len(result.Choices) == 0 {
+		return "", fmt.Errorf("llm: %s", resp.Status)
+	}
+
+	answer := result.Choices[0].Message.Content
+	fmt.Fprintln(w, answer)
+	return answer, nil
+}
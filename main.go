@@ -1,24 +1,36 @@
 package main
 
 import (
-	"flag"
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textarea"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
 // Download options (can be combined)
 type DownloadOptions struct {
-	Video   bool
-	Audio   bool
-	Subs    bool
-	Summary bool
-	Prompt  string
+	Video    bool         `json:"video"`
+	Audio    bool         `json:"audio"`
+	Subs     bool         `json:"subs"`
+	Summary  bool         `json:"summary"`
+	Chapters bool         `json:"chapters,omitempty"`
+	Prompt   string       `json:"prompt,omitempty"`
+	Embed    EmbedOptions `json:"embed,omitzero"` // into the video and audio files
 }
 
 func (d DownloadOptions) String() string {
@@ -35,12 +47,39 @@ func (d DownloadOptions) String() string {
 	if d.Summary {
 		parts = append(parts, "Summary")
 	}
+	if d.Chapters {
+		parts = append(parts, "Chapters")
+	}
 	if len(parts) == 0 {
 		return "Nothing"
 	}
 	return strings.Join(parts, " + ")
 }
 
+// isEmpty reports whether the options don't ask for anything.
+func (d DownloadOptions) isEmpty() bool {
+	return !(d.Video || d.Audio || d.Subs || d.Summary || d.Chapters)
+}
+
+// without returns the options with the given steps turned off.
+func (d DownloadOptions) without(steps []string) DownloadOptions {
+	for _, step := range steps {
+		switch step {
+		case "video":
+			d.Video = false
+		case "audio":
+			d.Audio = false
+		case "subs":
+			d.Subs = false
+		case "summary":
+			d.Summary = false
+		case "chapters":
+			d.Chapters = false
+		}
+	}
+	return d
+}
+
 // UI State
 type uiState int
 
@@ -58,31 +97,42 @@ type model struct {
 	checked      []bool // which options are checked
 	done         bool
 	quitting     bool
+	asking       bool // open the chat screen instead of downloading
 	title        string
 	outPath      string // full output path (dir + basename)
 	editing      bool
 	editBuf      string
 	state        uiState
-	editingField string // "path" or "prompt"
-	prompt       string // custom summary prompt
+	editingField string         // "path" or "prompt"
+	prompt       string         // custom summary prompt
+	promptArea   textarea.Model // multiline prompt editor
+	presets      []string       // preset names, cycled with tab
+	presetIdx    int            // index into presets of the active one, or -1
+	info         *videoInfo
+	infoErr      error           // why the video's details couldn't be fetched
+	input        textinput.Model // URL entry field
+	urlErr       string          // why the entered URL was rejected
+	recent       []historyEntry  // recent runs, newest first
+	histIdx      int             // which recent run the URL was recalled from, or -1
+	draft        string          // what was typed before recalling history
+	ctx          context.Context
 }
 
 // Message types for async operations
-type titleMsg string
+type infoMsg *videoInfo
 type errMsg error
 
-func fetchTitle(url string) tea.Cmd {
+func fetchTitle(ctx context.Context, url string) tea.Cmd {
 	return func() tea.Msg {
-		cmd := exec.Command("yt-dlp", "--get-title", url)
-		out, err := cmd.Output()
+		info, err := fetchInfo(ctx, url)
 		if err != nil {
 			return errMsg(err)
 		}
-		return titleMsg(strings.TrimSpace(string(out)))
+		return infoMsg(info)
 	}
 }
 
-func initialModel(url string) model {
+func initialModel(ctx context.Context, url string) model {
 	state := stateURLInput
 	if url != "" {
 		state = stateLoading
@@ -94,17 +144,38 @@ func initialModel(url string) model {
 	}
 
 	summaryLabel := "Summary"
-	if !claudeAvailable {
-		summaryLabel = "Summary (install claude cli)"
+	chaptersLabel := "Chapters"
+	if !llmAvailable {
+		summaryLabel = "Summary (" + llmErr.Error() + ")"
+		chaptersLabel = "Chapters (" + llmErr.Error() + ")"
 	}
 
+	input := textinput.New()
+	input.Prompt = ""
+	input.Placeholder = "https://www.youtube.com/watch?v=..."
+	input.TextStyle = filenameStyle
+	input.Cursor.Style = editStyle
+	input.Focus()
+
+	promptArea := textarea.New()
+	promptArea.ShowLineNumbers = false
+	promptArea.SetWidth(72)
+	promptArea.SetHeight(6)
+
 	return model{
-		url:     url,
-		choices: []string{"Video", "Audio", "Subtitles", summaryLabel},
-		checked: make([]bool, 4),
-		state:   state,
-		outPath: dir + "/video", // fallback
-		prompt:  defaultPrompt,
+		url:        url,
+		input:      input,
+		choices:    []string{"Video", "Audio", "Subtitles", summaryLabel, chaptersLabel},
+		checked:    make([]bool, 5),
+		state:      state,
+		outPath:    dir + "/video", // fallback
+		prompt:     cfg.prompt(),
+		promptArea: promptArea,
+		presets:    cfg.presetNames(),
+		presetIdx:  -1,
+		recent:     recentRuns(8),
+		histIdx:    -1,
+		ctx:        ctx,
 	}
 }
 
@@ -112,25 +183,28 @@ const defaultPrompt = "Summarize this transcript of a YouTube video. Provide a c
 
 func (m model) getOptions() DownloadOptions {
 	return DownloadOptions{
-		Video:   m.checked[0],
-		Audio:   m.checked[1],
-		Subs:    m.checked[2],
-		Summary: m.checked[3],
-		Prompt:  m.prompt,
+		Video:    m.checked[0],
+		Audio:    m.checked[1],
+		Subs:     m.checked[2],
+		Summary:  m.checked[3],
+		Chapters: m.checked[4],
+		Prompt:   m.prompt,
+		Embed:    cfg.Embed,
 	}
 }
 
 func (m model) Init() tea.Cmd {
 	if m.url != "" {
-		return fetchTitle(m.url)
+		return fetchTitle(m.ctx, m.url)
 	}
-	return nil
+	return textinput.Blink
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
-	case titleMsg:
-		m.title = string(msg)
+	case infoMsg:
+		m.info = msg
+		m.title = msg.Title
 		dir := "."
 		if outputDir != "" {
 			dir = outputDir
@@ -141,9 +215,23 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case errMsg:
 		m.state = stateMenu
+		m.infoErr = msg
 		// Keep fallback outPath
 		return m, nil
 
+	default:
+		// Cursor blinks and the like
+		if m.state == stateURLInput {
+			var cmd tea.Cmd
+			m.input, cmd = m.input.Update(msg)
+			return m, cmd
+		}
+		if m.editing && m.editingField == "prompt" {
+			var cmd tea.Cmd
+			m.promptArea, cmd = m.promptArea.Update(msg)
+			return m, cmd
+		}
+
 	case tea.KeyMsg:
 		// Handle URL input state
 		if m.state == stateURLInput {
@@ -152,29 +240,74 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.quitting = true
 				return m, tea.Quit
 			case tea.KeyEnter:
-				if m.url != "" {
-					m.state = stateLoading
-					return m, fetchTitle(m.url)
+				url, err := normalizeURL(m.input.Value())
+				if err != nil {
+					m.urlErr = err.Error()
+					return m, nil
 				}
-			case tea.KeyBackspace:
-				if len(m.url) > 0 {
-					m.url = m.url[:len(m.url)-1]
+				m.url = url
+				m.state = stateLoading
+				return m, fetchTitle(m.ctx, m.url)
+			case tea.KeyUp:
+				// Recall older URLs from history, like a shell
+				if m.histIdx < len(m.recent)-1 {
+					if m.histIdx == -1 {
+						m.draft = m.input.Value()
+					}
+					m.histIdx++
+					m.setInput(m.recent[m.histIdx].URL)
 				}
-			case tea.KeyRunes:
-				m.url += string(msg.Runes)
+				return m, nil
+			case tea.KeyDown:
+				if m.histIdx >= 0 {
+					m.histIdx--
+					if m.histIdx == -1 {
+						m.setInput(m.draft)
+					} else {
+						m.setInput(m.recent[m.histIdx].URL)
+					}
+				}
+				return m, nil
 			}
-			return m, nil
+
+			// Everything else is editing: cursor movement, ctrl+u/ctrl+w,
+			// pasting and so on
+			before := m.input.Value()
+			var cmd tea.Cmd
+			m.input, cmd = m.input.Update(msg)
+			if m.input.Value() != before {
+				m.histIdx = -1
+				m.urlErr = ""
+			}
+			return m, cmd
+		}
+
+		// Prompt editor: enter adds a line, so saving is ctrl+s
+		if m.editing && m.editingField == "prompt" {
+			switch msg.String() {
+			case "ctrl+s":
+				if prompt := strings.TrimSpace(m.promptArea.Value()); prompt != m.prompt {
+					m.prompt = prompt
+					m.presetIdx = -1
+				}
+				m.editing = false
+				m.promptArea.Blur()
+				return m, nil
+			case "esc":
+				m.editing = false
+				m.promptArea.Blur()
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.promptArea, cmd = m.promptArea.Update(msg)
+			return m, cmd
 		}
 
 		// Handle editing mode
 		if m.editing {
 			switch msg.Type {
 			case tea.KeyEnter:
-				if m.editingField == "path" {
-					m.outPath = m.editBuf
-				} else if m.editingField == "prompt" {
-					m.prompt = m.editBuf
-				}
+				m.outPath = m.editBuf
 				m.editing = false
 			case tea.KeyEscape:
 				m.editing = false
@@ -202,16 +335,16 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.cursor++
 			}
 		case " ", "x":
-			// Toggle checkbox (but not Summary if claude unavailable)
-			if m.cursor == 3 && !claudeAvailable {
-				// Can't toggle summary without claude
+			// Toggle checkbox (but not Summary or Chapters if there's no LLM)
+			if m.cursor >= 3 && !llmAvailable {
+				// Can't toggle summary without an LLM
 				break
 			}
 			m.checked[m.cursor] = !m.checked[m.cursor]
 		case "enter":
 			// Submit if at least one option selected
 			opts := m.getOptions()
-			if opts.Video || opts.Audio || opts.Subs || opts.Summary {
+			if !opts.isEmpty() {
 				m.done = true
 				return m, tea.Quit
 			}
@@ -220,17 +353,47 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.editingField = "path"
 			m.editBuf = m.outPath
 		case "p":
-			// Only allow prompt editing if claude is available
-			if claudeAvailable {
+			// Only allow prompt editing if there's an LLM to prompt
+			if llmAvailable {
 				m.editing = true
 				m.editingField = "prompt"
-				m.editBuf = m.prompt
+				m.promptArea.SetValue(m.prompt)
+				return m, m.promptArea.Focus()
+			}
+		case "a":
+			if llmAvailable {
+				m.asking = true
+				return m, tea.Quit
+			}
+		case "tab", "shift+tab":
+			// Cycle through presets, with the configured prompt in between
+			if llmAvailable && len(m.presets) > 0 {
+				n := len(m.presets) + 1
+				step := 1
+				if msg.String() == "shift+tab" {
+					step = n - 1
+				}
+				m.presetIdx = (m.presetIdx+1+step)%n - 1
+				if m.presetIdx == -1 {
+					m.prompt = cfg.prompt()
+				} else {
+					m.prompt, _ = cfg.preset(m.presets[m.presetIdx])
+				}
+				// Picking a preset implies wanting a summary
+				m.checked[3] = true
 			}
 		}
 	}
 	return m, nil
 }
 
+// setInput replaces the URL field's contents, leaving the cursor at the end.
+func (m *model) setInput(s string) {
+	m.input.SetValue(s)
+	m.input.CursorEnd()
+	m.urlErr = ""
+}
+
 func sanitizeFilename(s string) string {
 	// Remove or replace characters that are problematic in filenames
 	replacer := strings.NewReplacer(
@@ -268,6 +431,9 @@ var (
 	editStyle = lipgloss.NewStyle().
 			Foreground(lipgloss.Color("yellow")).
 			Bold(true)
+
+	errorStyle = lipgloss.NewStyle().
+			Foreground(lipgloss.Color("203"))
 )
 
 func (m model) View() string {
@@ -278,8 +444,17 @@ func (m model) View() string {
 	// URL input state
 	if m.state == stateURLInput {
 		s := titleStyle.Render("Enter YouTube URL:") + "\n\n"
-		s += filenameStyle.Render(m.url) + editStyle.Render("▌") + "\n\n"
-		s += dimStyle.Render("enter to continue • ctrl+c to quit")
+		s += m.input.View() + "\n"
+		if m.urlErr != "" {
+			s += errorStyle.Render("✗ "+m.urlErr) + "\n"
+		}
+		s += "\n"
+		hints := "enter to continue • ctrl+c to quit"
+		if len(m.recent) > 0 {
+			s += m.historyView() + "\n"
+			hints = "↑/↓ recent URLs • " + hints
+		}
+		s += dimStyle.Render(hints)
 		return s
 	}
 
@@ -290,6 +465,14 @@ func (m model) View() string {
 
 	// Menu state
 	s := titleStyle.Render("What would you like to download?") + "\n\n"
+	// Downloading will fail the same way, so say why up front
+	var authErr *authError
+	switch {
+	case errors.As(m.infoErr, &authErr):
+		s += errorStyle.Render("✗ "+authErr.Error()) + "\n\n"
+	case m.infoErr != nil:
+		s += errorStyle.Render("✗ Couldn't fetch the video's details: "+m.infoErr.Error()) + "\n\n"
+	}
 
 	for i, choice := range m.choices {
 		cursor := "  "
@@ -312,23 +495,27 @@ func (m model) View() string {
 	if m.editing {
 		if m.editingField == "path" {
 			s += editStyle.Render("Output: ") + m.editBuf + editStyle.Render("▌") + "\n"
+			s += dimStyle.Render("enter to confirm • esc to cancel")
 		} else {
-			s += editStyle.Render("Prompt: ") + m.editBuf + editStyle.Render("▌") + "\n"
+			s += editStyle.Render("Prompt:") + "\n" + m.promptArea.View() + "\n"
+			s += dimStyle.Render("ctrl+s to save • esc to cancel")
 		}
-		s += dimStyle.Render("enter to confirm • esc to cancel")
 	} else {
 		s += dimStyle.Render("Output: ") + filenameStyle.Render(m.getFilenames()) + "\n"
-		if claudeAvailable && m.checked[3] {
+		if (m.checked[0] || m.checked[1]) && cfg.Embed != (EmbedOptions{}) {
+			s += dimStyle.Render("Embed: ") + strings.ReplaceAll(cfg.Embed.String(), ",", ", ") + "\n"
+		}
+		if llmAvailable && m.checked[3] {
 			// Show truncated prompt if summary is selected
-			promptPreview := m.prompt
-			if len(promptPreview) > 50 {
-				promptPreview = promptPreview[:47] + "..."
+			label := "Prompt: "
+			if m.presetIdx >= 0 {
+				label = "Prompt (" + m.presets[m.presetIdx] + "): "
 			}
-			s += dimStyle.Render("Prompt: ") + promptPreview + "\n"
+			s += dimStyle.Render(label) + promptPreview(m.prompt, 50) + "\n"
 		}
 		hints := "↑/↓ navigate • space toggle • enter download • e edit path"
-		if claudeAvailable {
-			hints += " • p edit prompt"
+		if llmAvailable {
+			hints += " • p edit prompt • tab preset • a ask questions"
 		}
 		hints += " • q quit"
 		s += "\n" + dimStyle.Render(hints)
@@ -336,6 +523,37 @@ func (m model) View() string {
 	return s
 }
 
+// promptPreview returns the first line of prompt, cut to max characters.
+func promptPreview(prompt string, max int) string {
+	first, _, multiline := strings.Cut(prompt, "\n")
+	r := []rune(first)
+	if len(r) > max {
+		return string(r[:max-3]) + "..."
+	}
+	if multiline {
+		return first + " ..."
+	}
+	return first
+}
+
+// historyView lists recent runs, highlighting the one being recalled.
+func (m model) historyView() string {
+	s := dimStyle.Render("Recent:") + "\n"
+	for i, e := range m.recent {
+		label := e.Title
+		if label == "" {
+			label = e.URL
+		}
+		when := e.Time.Local().Format("Jan 2 15:04")
+		if i == m.histIdx {
+			s += "▸ " + selectedStyle.Render(label) + " " + dimStyle.Render(when) + "\n"
+		} else {
+			s += "  " + normalStyle.Render(label) + " " + dimStyle.Render(when) + "\n"
+		}
+	}
+	return s
+}
+
 func (m model) getFilenames() string {
 	opts := m.getOptions()
 	var exts []string
@@ -349,6 +567,9 @@ func (m model) getFilenames() string {
 	if opts.Subs {
 		exts = append(exts, ".txt")
 	}
+	if opts.Chapters {
+		exts = append(exts, ".chapters.txt")
+	}
 	if opts.Summary {
 		exts = append(exts, "(summary to stdout)")
 	}
@@ -390,63 +611,182 @@ func (m model) getFilenames() string {
 	return result
 }
 
-func runDownload(url string, opts DownloadOptions) error {
+// plannedSteps lists the steps a run will perform, in order.
+func plannedSteps(opts DownloadOptions) []string {
+	var steps []string
+	if opts.Video {
+		steps = append(steps, "video")
+	}
+	if opts.Audio {
+		steps = append(steps, "audio")
+	}
+	if opts.Subs {
+		steps = append(steps, "subs")
+	}
+	if opts.Summary {
+		steps = append(steps, "summary")
+	}
+	if opts.Chapters {
+		steps = append(steps, "chapters")
+	}
+	return steps
+}
+
+// runResult records what a run got done.
+type runResult struct {
+	Completed []string // steps that finished
+	Files     []string // files written
+	Summary   string   // the summary, if one was made
+}
+
+// runDownload runs every requested step, skipping the ones an earlier,
+// interrupted run already got done. The result covers whatever finished,
+// done included, even when an error cut the run short; if ctx is
+// cancelled part way through, the error wraps context.Canceled.
+//
+// Progress shows in a spinner, unless progress is set or there's no
+// terminal, in which case it's reported a line at a time.
+func runDownload(ctx context.Context, url string, opts DownloadOptions, done runResult, progress func(string)) (runResult, error) {
+	res := runResult{
+		Completed: slices.Clone(done.Completed),
+		Files:     slices.Clone(done.Files),
+		Summary:   done.Summary,
+	}
+	opts = opts.without(done.Completed)
+
+	// With more than one step, fetch the metadata once for all of them.
+	// If that fails, each step fetches the page itself as usual
+	if len(plannedSteps(opts)) > 1 || cachedVideoInfo(url) != nil {
+		if unshare, err := shareInfo(ctx, url); err == nil {
+			defer unshare()
+		}
+	}
+	if progress == nil && !isTerminal(os.Stderr) {
+		progress = func(status string) { fmt.Fprintf(os.Stderr, "%s...\n", status) }
+		ctx = withRateNotice(ctx, func(wait time.Duration) {
+			if wait > 0 {
+				progress(rateStatus(wait))
+			}
+		})
+	}
+
 	// Run file downloads with spinner
 	if opts.Video || opts.Audio || opts.Subs {
-		if err := runWithSpinner(url, opts); err != nil {
-			return err
+		var got runResult
+		var err error
+		if progress == nil {
+			got, err = runWithSpinner(ctx, url, opts)
+		} else {
+			got, err = runPlain(ctx, url, opts, progress)
+		}
+		res.Completed = append(res.Completed, got.Completed...)
+		res.Files = append(res.Files, got.Files...)
+		if err != nil {
+			return res, err
 		}
 	}
 
 	// Summary runs separately (has its own output)
 	if opts.Summary {
+		if progress != nil {
+			progress("Summarizing")
+		}
 		prompt := opts.Prompt
 		if prompt == "" {
 			prompt = defaultPrompt
 		}
-		return downloadSummary(url, prompt)
+		summary, err := downloadSummary(ctx, url, prompt)
+		if err != nil {
+			if ctx.Err() != nil {
+				return res, ctx.Err()
+			}
+			return res, err
+		}
+		res.Summary = summary
+		res.Completed = append(res.Completed, "summary")
+		stepDone(ctx)(runResult{Completed: []string{"summary"}, Summary: summary})
+	}
+
+	// Chapters go last so they can be embedded in what was downloaded
+	if opts.Chapters {
+		if progress != nil {
+			progress("Making chapters")
+		}
+		files, err := writeChapters(ctx, url, res.Files)
+		res.Files = append(res.Files, files...)
+		if err != nil {
+			if ctx.Err() != nil {
+				return res, ctx.Err()
+			}
+			return res, err
+		}
+		res.Completed = append(res.Completed, "chapters")
+		stepDone(ctx)(runResult{Completed: []string{"chapters"}, Files: files})
 	}
 
-	return nil
+	return res, nil
 }
 
 // Spinner model for download progress
 type downloadModel struct {
-	spinner spinner.Model
-	status  string
-	done    bool
-	err     error
-	url     string
-	opts    DownloadOptions
-	steps   []string // what to download, in order
-	step    int      // current step index
+	spinner    spinner.Model
+	status     string
+	done       bool
+	err        error
+	url        string
+	opts       DownloadOptions
+	steps      []string // what to download, in order
+	step       int      // current step index
+	completed  []string // steps that finished successfully
+	files      []string // files the finished steps wrote
+	attempt    int      // attempt number for the current step
+	retrying   bool     // waiting out a backoff before the next attempt
+	detail     string   // what the current step is up to, if it says
+	progress   chan string
+	ctx        context.Context
+	cancel     context.CancelFunc
+	cancelling bool
 }
 
-type downloadDoneMsg struct{ err error }
+type downloadDoneMsg struct {
+	files []string
+	err   error
+}
+type retryStepMsg struct{}
+type stepProgressMsg string
+
+// waitForProgress delivers the next progress update from the running step.
+func waitForProgress(ch chan string) tea.Cmd {
+	return func() tea.Msg {
+		return stepProgressMsg(<-ch)
+	}
+}
 
-func initialDownloadModel(url string, opts DownloadOptions) downloadModel {
+func initialDownloadModel(ctx context.Context, url string, opts DownloadOptions) downloadModel {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
 	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("212"))
 
-	// Build list of steps based on options
+	// Build list of steps based on options; summary and chapters run
+	// outside the spinner
 	var steps []string
-	if opts.Video {
-		steps = append(steps, "video")
-	}
-	if opts.Audio {
-		steps = append(steps, "audio")
-	}
-	if opts.Subs {
-		steps = append(steps, "subs")
+	for _, step := range plannedSteps(opts) {
+		if step != "summary" && step != "chapters" {
+			steps = append(steps, step)
+		}
 	}
 
+	ctx, cancel := context.WithCancel(ctx)
 	dm := downloadModel{
-		spinner: s,
-		url:     url,
-		opts:    opts,
-		steps:   steps,
-		step:    0,
+		spinner:  s,
+		url:      url,
+		opts:     opts,
+		steps:    steps,
+		step:     0,
+		attempt:  1,
+		progress: make(chan string),
+		ctx:      ctx,
+		cancel:   cancel,
 	}
 	dm.status = dm.getStatusText()
 
@@ -454,6 +794,9 @@ func initialDownloadModel(url string, opts DownloadOptions) downloadModel {
 }
 
 func (m downloadModel) getStatusText() string {
+	if m.cancelling {
+		return "Cancelling..."
+	}
 	if m.step >= len(m.steps) {
 		return "Done!"
 	}
@@ -462,14 +805,16 @@ func (m downloadModel) getStatusText() string {
 	total := len(m.steps)
 	current := m.step + 1
 
-	var desc string
-	switch stepName {
-	case "video":
-		desc = "Downloading video"
-	case "audio":
-		desc = "Downloading audio"
-	case "subs":
-		desc = "Downloading subtitles"
+	if m.attempt > 1 {
+		if stepName == "subs" {
+			stepName = "subtitles"
+		}
+		return fmt.Sprintf("Retrying %s (attempt %d/%d)...", stepName, m.attempt, maxAttempts)
+	}
+
+	desc := stepDescription(stepName)
+	if m.detail != "" {
+		desc = m.detail
 	}
 
 	if total > 1 {
@@ -482,49 +827,125 @@ type startDownloadMsg struct{}
 
 func (m downloadModel) Init() tea.Cmd {
 	// Start spinner first, then trigger download on next tick
-	return tea.Batch(m.spinner.Tick, func() tea.Msg {
+	return tea.Batch(m.spinner.Tick, waitForProgress(m.progress), func() tea.Msg {
 		return startDownloadMsg{}
 	})
 }
 
+// report sends a progress update from the running step to the UI.
+func (m downloadModel) report(status string) {
+	select {
+	case m.progress <- status:
+	case <-m.ctx.Done():
+	}
+}
+
 func (m downloadModel) runCurrentStep() tea.Cmd {
 	return func() tea.Msg {
 		if m.step >= len(m.steps) {
 			return downloadDoneMsg{err: nil}
 		}
 
-		var err error
-		switch m.steps[m.step] {
-		case "video":
-			err = doDownloadVideo(m.url)
-		case "audio":
-			err = doDownloadAudio(m.url)
-		case "subs":
-			err = doDownloadSubs(m.url)
-		}
-		return downloadDoneMsg{err: err}
+		ctx := withRateNotice(m.ctx, func(wait time.Duration) {
+			if wait > 0 {
+				m.report(rateStatus(wait))
+			} else {
+				m.report("")
+			}
+		})
+		files, err := runStep(ctx, m.steps[m.step], m.url, m.opts, m.report)
+		return downloadDoneMsg{files: files, err: err}
+	}
+}
+
+// runStep runs one of the file download steps, returning the files it
+// wrote.
+func runStep(ctx context.Context, step, url string, opts DownloadOptions, progress func(string)) ([]string, error) {
+	switch step {
+	case "video":
+		return doDownloadVideo(ctx, url, opts.Embed)
+	case "audio":
+		return doDownloadAudio(ctx, url, opts.Embed)
+	case "subs":
+		return doDownloadSubs(ctx, url, progress)
 	}
+	return nil, fmt.Errorf("unknown step %q", step)
+}
+
+func stepDescription(step string) string {
+	switch step {
+	case "video":
+		return "Downloading video"
+	case "audio":
+		return "Downloading audio"
+	case "subs":
+		return "Downloading subtitles"
+	}
+	return step
 }
 
 func (m downloadModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		if msg.String() == "ctrl+c" {
-			return m, tea.Quit
+		if msg.String() == "ctrl+c" && !m.cancelling {
+			m.cancelling = true
+			m.status = m.getStatusText()
+			m.cancel()
+			if m.retrying {
+				// Nothing is running, so there's nothing to wait for
+				m.err = m.ctx.Err()
+				m.done = true
+				return m, tea.Quit
+			}
+			// Otherwise the running step reports back once it's been
+			// killed, so nothing is left running when we quit
 		}
 
 	case startDownloadMsg:
 		return m, m.runCurrentStep()
 
+	case retryStepMsg:
+		if m.cancelling {
+			return m, nil
+		}
+		m.retrying = false
+		return m, m.runCurrentStep()
+
+	case stepProgressMsg:
+		if !m.cancelling && !m.retrying {
+			m.detail = string(msg)
+			m.status = m.getStatusText()
+		}
+		return m, waitForProgress(m.progress)
+
 	case downloadDoneMsg:
+		if m.ctx.Err() != nil {
+			m.err = m.ctx.Err()
+			m.done = true
+			return m, tea.Quit
+		}
 		if msg.err != nil {
+			if m.attempt < maxAttempts && isRetryable(msg.err) {
+				m.attempt++
+				m.retrying = true
+				m.detail = ""
+				m.status = m.getStatusText()
+				return m, tea.Tick(retryDelay(m.attempt), func(time.Time) tea.Msg {
+					return retryStepMsg{}
+				})
+			}
 			m.err = msg.err
 			m.done = true
 			return m, tea.Quit
 		}
 
 		// Advance to next step
+		m.completed = append(m.completed, m.steps[m.step])
+		m.files = append(m.files, msg.files...)
+		stepDone(m.ctx)(runResult{Completed: []string{m.steps[m.step]}, Files: msg.files})
 		m.step++
+		m.attempt = 1
+		m.detail = ""
 		if m.step < len(m.steps) {
 			m.status = m.getStatusText()
 			return m, m.runCurrentStep()
@@ -549,19 +970,70 @@ func (m downloadModel) View() string {
 	return m.spinner.View() + " " + m.status
 }
 
-func runWithSpinner(url string, opts DownloadOptions) error {
-	p := tea.NewProgram(initialDownloadModel(url, opts), tea.WithOutput(os.Stderr))
+func runWithSpinner(ctx context.Context, url string, opts DownloadOptions) (runResult, error) {
+	started := time.Now()
+	dm := initialDownloadModel(ctx, url, opts)
+	defer dm.cancel()
+
+	// Signals cancel ctx instead, which stops the running step cleanly
+	p := tea.NewProgram(dm, tea.WithOutput(os.Stderr), tea.WithoutSignalHandler())
 	finalModel, err := p.Run()
 	if err != nil {
-		return err
+		return runResult{}, err
+	}
+
+	dm = finalModel.(downloadModel)
+	// Partial files are kept for resuming after a shutdown
+	if errors.Is(dm.err, context.Canceled) && !keepPartial && !shuttingDown(ctx) {
+		removePartials(outputSearchDir(), started)
+	}
+	return runResult{Completed: dm.completed, Files: dm.files}, dm.err
+}
+
+// runPlain runs the same steps as runWithSpinner, reporting each to
+// progress instead, for when there's no terminal to draw a spinner on
+// (cron jobs, tuber watch in the background, tuber serve).
+func runPlain(ctx context.Context, url string, opts DownloadOptions, progress func(string)) (runResult, error) {
+	started := time.Now()
+	var res runResult
+	for _, step := range plannedSteps(opts) {
+		if step == "summary" || step == "chapters" {
+			continue
+		}
+		progress(stepDescription(step))
+		var files []string
+		err := withRetry(ctx, func() error {
+			var err error
+			files, err = runStep(ctx, step, url, opts, progress)
+			return err
+		}, func(attempt int, delay time.Duration, err error) {
+			progress(fmt.Sprintf("Retrying %s in %s (attempt %d/%d)", step, delay.Round(time.Second), attempt, maxAttempts))
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				if !keepPartial && !shuttingDown(ctx) {
+					removePartials(outputSearchDir(), started)
+				}
+				return res, ctx.Err()
+			}
+			return res, err
+		}
+		res.Completed = append(res.Completed, step)
+		res.Files = append(res.Files, files...)
+		stepDone(ctx)(runResult{Completed: []string{step}, Files: files})
 	}
+	return res, nil
+}
 
-	dm := finalModel.(downloadModel)
-	return dm.err
+// isTerminal reports whether f is a terminal rather than a file or pipe.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
 }
 
 var outputDir string
 var customOutPath string
+var keepPartial bool
 
 func getOutputPattern(ext string) string {
 	if customOutPath != "" {
@@ -574,33 +1046,105 @@ func getOutputPattern(ext string) string {
 	return dir + "/%(title)s" + ext
 }
 
-func doDownloadVideo(url string) error {
+// outputSearchDir returns the directory yt-dlp writes into.
+func outputSearchDir() string {
+	searchDir := "."
+	if outputDir != "" {
+		searchDir = outputDir
+	}
+	if customOutPath != "" {
+		// Extract directory from custom path
+		lastSlash := strings.LastIndex(customOutPath, "/")
+		if lastSlash > 0 {
+			searchDir = customOutPath[:lastSlash]
+		}
+	}
+	return searchDir
+}
+
+// partialFile matches the in-progress files yt-dlp leaves behind when it's
+// interrupted: .part/.ytdl files, fragments, and unmerged format streams.
+var partialFile = regexp.MustCompile(`\.(part|ytdl)$|\.part-Frag\d+|\.f\d+\.[^.]+$|\.temp\.[^.]+$`)
+
+// removePartials deletes partial download files in dir modified since the
+// given time.
+func removePartials(dir string, since time.Time) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !partialFile.MatchString(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().Before(since) {
+			continue
+		}
+		os.Remove(filepath.Join(dir, entry.Name()))
+	}
+}
+
+// ytdlpCommand builds a yt-dlp invocation that is killed, along with any
+// children, when ctx is cancelled. The configured credentials, network
+// settings and bandwidth cap are added to args. rawURL is what it fetches
+// from, for the bandwidth cap: args can't say, since extra args like
+// --referer hold URLs too and the source may be shared metadata.
+func ytdlpCommand(ctx context.Context, rawURL string, args ...string) *exec.Cmd {
+	if limit := cfg.RateLimit.forHost(urlHost(rawURL)).LimitRate; limit != "" {
+		args = append([]string{"--limit-rate", limit}, args...)
+	}
+	args = slices.Concat(cfg.Auth.ytdlpArgs(), cfg.Network.ytdlpArgs(), args)
+	cmd := exec.CommandContext(ctx, "yt-dlp", args...)
+	setProcessGroup(cmd)
+	cmd.WaitDelay = 5 * time.Second
+	return cmd
+}
+
+// printFilepath makes yt-dlp print each finished file's final path, which
+// is the only reliable way to know what %(title)s expanded to.
+var printFilepath = []string{"--print", "after_move:filepath"}
+
+// printSubtitlePaths does the same for subtitles, which with
+// --skip-download are never moved, one path per line.
+var printSubtitlePaths = []string{"--print", "after_video:%(requested_subtitles.:.filepath)#l"}
+
+func doDownloadVideo(ctx context.Context, url string, embed EmbedOptions) ([]string, error) {
 	args := []string{
 		"-f", "bestvideo[ext=mp4]+bestaudio[ext=m4a]/best[ext=mp4]/best",
 		"--merge-output-format", "mp4",
 		"-q", "--no-warnings",
 	}
+	args = append(args, embed.ytdlpArgs(true)...)
+	args = append(args, printFilepath...)
 	args = append(args, "-o", getOutputPattern(".%(ext)s"))
-	args = append(args, url)
-	cmd := exec.Command("yt-dlp", args...)
-	return cmd.Run()
+	args = append(args, cfg.YtdlpArgs.forStep("video")...)
+	args = append(args, ytdlpSource(url)...)
+	out, err := ytdlpOutput(ctx, url, args...)
+	return outputLines(out), err
 }
 
-func doDownloadAudio(url string) error {
+func doDownloadAudio(ctx context.Context, url string, embed EmbedOptions) ([]string, error) {
 	args := []string{
 		"-x",
 		"--audio-format", "mp3",
 		"--audio-quality", "0",
 		"-q", "--no-warnings",
 	}
+	args = append(args, embed.ytdlpArgs(false)...)
+	args = append(args, printFilepath...)
 	args = append(args, "-o", getOutputPattern(".%(ext)s"))
-	args = append(args, url)
-	cmd := exec.Command("yt-dlp", args...)
-	return cmd.Run()
+	args = append(args, cfg.YtdlpArgs.forStep("audio")...)
+	args = append(args, ytdlpSource(url)...)
+	out, err := ytdlpOutput(ctx, url, args...)
+	return outputLines(out), err
 }
 
-func doDownloadSubs(url string) error {
-	cmd := exec.Command("yt-dlp",
+// doDownloadSubs saves the video's subtitles as text. If it has none and a
+// transcriber is set up, it transcribes the audio instead, telling
+// progress how that's going.
+func doDownloadSubs(ctx context.Context, url string, progress func(string)) ([]string, error) {
+	args := []string{
 		"--write-subs",
 		"--write-auto-subs",
 		"--sub-lang", "en",
@@ -608,106 +1152,171 @@ func doDownloadSubs(url string) error {
 		"--skip-download",
 		"-q", "--no-warnings",
 		"-o", getOutputPattern(".%(ext)s"),
-		url,
-	)
-	if err := cmd.Run(); err != nil {
-		return err
+	}
+	args = append(args, printSubtitlePaths...)
+	args = append(args, cfg.YtdlpArgs.forStep("subs")...)
+	out, err := ytdlpOutput(ctx, url, append(args, ytdlpSource(url)...)...)
+	if err != nil {
+		return nil, err
 	}
 
-	// Find and process the vtt file
-	searchDir := "."
-	if outputDir != "" {
-		searchDir = outputDir
+	// Only the files yt-dlp says it wrote: anything else in the output
+	// directory is some other video's
+	var vtts []string
+	for _, path := range outputLines(out) {
+		if strings.HasSuffix(path, ".vtt") {
+			vtts = append(vtts, path)
+		}
 	}
-	if customOutPath != "" {
-		// Extract directory from custom path
-		lastSlash := strings.LastIndex(customOutPath, "/")
-		if lastSlash > 0 {
-			searchDir = customOutPath[:lastSlash]
+	if len(vtts) > 0 || cfg.Transcriber.Command == "" {
+		return processSubtitles(vtts, url), nil
+	}
+
+	vttPath, err := transcribe(ctx, url, outputSearchDir(), progress)
+	if err != nil {
+		return nil, err
+	}
+	// A custom path may be a yt-dlp template, which only yt-dlp can fill in
+	if customOutPath != "" && !strings.Contains(customOutPath, "%(") {
+		if err := os.Rename(vttPath, customOutPath+".vtt"); err != nil {
+			return nil, err
 		}
+		vttPath = customOutPath + ".vtt"
 	}
-	return processSubtitles(searchDir)
+	return processSubtitles([]string{vttPath}, url), nil
 }
 
-func processSubtitles(dir string) error {
-	// Find .vtt files in directory
-	entries, err := os.ReadDir(dir)
-	if err != nil {
-		return err
+// outputLines splits yt-dlp's stdout into non-empty lines.
+func outputLines(out []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(string(out), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
 	}
+	return lines
+}
 
-	for _, entry := range entries {
-		if strings.HasSuffix(entry.Name(), ".vtt") {
-			vttPath := dir + "/" + entry.Name()
-			txtPath := dir + "/" + strings.TrimSuffix(entry.Name(), ".vtt") + ".txt"
-			if err := dedupeVTT(vttPath, txtPath); err != nil {
-				continue
-			}
-			// Remove the original vtt file
-			os.Remove(vttPath)
+// processSubtitles converts url's .vtt files to deduplicated text,
+// returning the paths of the .txt files written. Each is added to the
+// search index as the transcript of url.
+func processSubtitles(vtts []string, url string) []string {
+	var written []string
+	for _, vttPath := range vtts {
+		txtPath := strings.TrimSuffix(vttPath, ".vtt") + ".txt"
+		if err := dedupeVTT(vttPath, txtPath); err != nil {
+			continue
 		}
+		written = append(written, txtPath)
+		if content, err := os.ReadFile(vttPath); err == nil {
+			indexTranscript(url, txtPath, parseVTT(string(content)))
+		}
+		// Remove the original vtt file
+		os.Remove(vttPath)
 	}
-	return nil
+	return written
 }
 
-func downloadSummary(url string, prompt string) error {
+// downloadSummary summarizes a video, printing the summary to stdout and
+// returning it.
+func downloadSummary(ctx context.Context, url string, prompt string) (string, error) {
 	fmt.Fprintln(os.Stderr, "📝 Fetching subtitles for summary...")
 
-	// Create temp dir for subtitle download
-	tmpDir, err := os.MkdirTemp("", "tuber-summary-")
+	_, cues, err := fetchCues(ctx, url, os.Stderr)
 	if err != nil {
-		return fmt.Errorf("failed to create temp dir: %w", err)
+		return "", err
 	}
-	defer os.RemoveAll(tmpDir)
 
-	// Download subs to temp dir
-	cmd := exec.Command("yt-dlp",
-		"--write-subs",
-		"--write-auto-subs",
-		"--sub-lang", "en",
-		"--sub-format", "vtt",
-		"--skip-download",
-		"-o", tmpDir+"/%(title)s.%(ext)s",
-		url,
-	)
-	cmd.Stdout = os.Stderr
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to download subtitles: %w", err)
-	}
+	fmt.Fprint(os.Stderr, "\n🤖 Generating summary...\n\n")
 
-	// Find the vtt file
-	entries, err := os.ReadDir(tmpDir)
+	req := llmRequest{Prompt: prompt + timestampInstructions, Context: timestampedTranscript(cues)}
+	summary, err := newLLM(cfg.LLM).complete(ctx, req, io.Discard)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	var vttPath string
-	for _, entry := range entries {
-		if strings.HasSuffix(entry.Name(), ".vtt") {
-			vttPath = tmpDir + "/" + entry.Name()
-			break
-		}
+	// Link the cited times back into the video, checking each one is real
+	summary, invalid := linkTimestamps(strings.TrimSpace(summary), cues, youtubeID(url))
+	for _, ts := range invalid {
+		fmt.Fprintf(os.Stderr, "⚠ summary cites %s, which isn't in the transcript\n", ts)
+	}
+
+	// Summary goes to stdout so it can be captured
+	fmt.Println(summary)
+	return summary, nil
+}
+
+// fetchTranscript downloads a video's subtitles to a temp dir and returns
+// them as deduplicated plain text.
+func fetchTranscript(ctx context.Context, url string) (string, error) {
+	// Create temp dir for subtitle download
+	tmpDir, err := os.MkdirTemp("", "tuber-summary-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp dir: %w", err)
 	}
+	defer os.RemoveAll(tmpDir)
 
-	if vttPath == "" {
-		return fmt.Errorf("no subtitles found for this video")
+	vttPath, err := downloadVTT(ctx, url, tmpDir, os.Stderr)
+	if err != nil {
+		return "", err
 	}
 
 	// Extract and dedupe the text
 	transcript, err := extractText(vttPath)
 	if err != nil {
-		return fmt.Errorf("failed to extract text: %w", err)
+		return "", fmt.Errorf("failed to extract text: %w", err)
 	}
+	return transcript, nil
+}
 
-	fmt.Fprintln(os.Stderr, "\n🤖 Generating summary...\n")
+// downloadVTT fetches a video's English subtitles (uploaded or automatic)
+// into dir and returns the .vtt file's path. yt-dlp's progress goes to log.
+func downloadVTT(ctx context.Context, url, dir string, log io.Writer) (string, error) {
+	err := withRetry(ctx, func() error {
+		args := []string{
+			"--write-subs",
+			"--write-auto-subs",
+			"--sub-lang", "en",
+			"--sub-format", "vtt",
+			"--skip-download",
+			"-o", dir + "/%(title)s.%(ext)s",
+		}
+		args = append(args, cfg.YtdlpArgs.forStep("subs")...)
+		args = append(args, ytdlpSource(url)...)
+		if err := waitTurn(ctx, url); err != nil {
+			return err
+		}
+		var stderr bytes.Buffer
+		cmd := ytdlpCommand(ctx, url, args...)
+		cmd.Stdout = log
+		cmd.Stderr = io.MultiWriter(log, &stderr)
+		if err := cmd.Run(); err != nil {
+			return newYtdlpError(err, stderr.String())
+		}
+		return nil
+	}, func(attempt int, delay time.Duration, err error) {
+		fmt.Fprintf(log, "↻ Retrying subtitles in %s (attempt %d/%d)...\n", delay.Round(time.Second), attempt, maxAttempts)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to download subtitles: %w", err)
+	}
+
+	// Find the vtt file
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".vtt") {
+			return dir + "/" + entry.Name(), nil
+		}
+	}
 
-	// Pipe to claude - summary goes to stdout so it can be captured
-	cmd = exec.Command("claude", "-p", prompt)
-	cmd.Stdin = strings.NewReader(transcript)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	if cfg.Transcriber.Command == "" {
+		return "", fmt.Errorf("no subtitles found for this video (set transcriber.command to transcribe it locally)")
+	}
+	fmt.Fprintln(log, "🎙 No subtitles, transcribing locally...")
+	return transcribe(ctx, url, dir, logProgress(log))
 }
 
 // extractText returns deduplicated plain text from a VTT file
@@ -818,101 +1427,40 @@ func stripTags(s string) string {
 	return result.String()
 }
 
-var claudeAvailable bool
-
 func main() {
-	// Check for yt-dlp
-	if _, err := exec.LookPath("yt-dlp"); err != nil {
-		fmt.Fprintln(os.Stderr, "Error: yt-dlp not found in PATH")
-		fmt.Fprintln(os.Stderr, "Install it from: https://github.com/yt-dlp/yt-dlp")
-		os.Exit(1)
-	}
-
-	// Check for claude (optional)
-	_, err := exec.LookPath("claude")
-	claudeAvailable = err == nil
-
-	// Flags for quick access (can be combined)
-	videoFlag := flag.Bool("v", false, "Download video")
-	audioFlag := flag.Bool("a", false, "Download audio (mp3)")
-	subsFlag := flag.Bool("s", false, "Download subtitles (text)")
-	sumFlag := flag.Bool("sum", false, "Summarize video using AI")
-	promptFlag := flag.String("p", "", "Custom prompt for summary")
-	outFlag := flag.String("o", "", "Output directory (default: current directory)")
-	flag.Parse()
-
-	outputDir = *outFlag
-
-	args := flag.Args()
-	var url string
-	if len(args) >= 1 {
-		url = args[0]
-	}
-
-	// Build options from flags
-	prompt := defaultPrompt
-	if *promptFlag != "" {
-		prompt = *promptFlag
-	}
-
-	opts := DownloadOptions{
-		Video:   *videoFlag,
-		Audio:   *audioFlag,
-		Subs:    *subsFlag,
-		Summary: *sumFlag,
-		Prompt:  prompt,
-	}
-
-	// Check if summary requested but claude not available
-	if opts.Summary && !claudeAvailable {
-		fmt.Fprintln(os.Stderr, "Error: -sum requires claude cli")
-		fmt.Fprintln(os.Stderr, "Install it from: https://claude.ai/download")
-		os.Exit(1)
-	}
-
-	flagSet := opts.Video || opts.Audio || opts.Subs || opts.Summary
-
-	// If flag set but no URL, show usage
-	if flagSet && url == "" {
-		fmt.Println("Usage: tuber [flags] <url>")
-		fmt.Println("\nFlags (can be combined):")
-		fmt.Println("  -v             Download video")
-		fmt.Println("  -a             Download audio (mp3)")
-		fmt.Println("  -s             Download subtitles (text)")
-		fmt.Println("  -sum           Summarize video using AI")
-		fmt.Println("  -p <prompt>    Custom prompt for summary")
-		fmt.Println("  -o <dir>       Output directory")
-		fmt.Println("\nExamples:")
-		fmt.Println("  tuber -a -s <url>                    Download audio and subtitles")
-		fmt.Println("  tuber -sum -p \"List key points\" <url>  Summarize with custom prompt")
-		fmt.Println("\nWithout flags, opens interactive menu.")
+	var err error
+	cfg, err = loadConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: reading config: %v\n", err)
 		os.Exit(1)
 	}
 
-	// If no flag (or no URL), show interactive menu
-	if !flagSet {
-		p := tea.NewProgram(initialModel(url))
-		m, err := p.Run()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
-		}
+	// Check for an LLM (optional)
+	llmErr = checkLLM(cfg.LLM)
+	llmAvailable = llmErr == nil
 
-		finalModel := m.(model)
-		if finalModel.quitting {
-			os.Exit(0)
-		}
-		opts = finalModel.getOptions()
-		url = finalModel.url
-		customOutPath = finalModel.outPath
+	outputDir = cfg.OutputDir
+	keepPartial = cfg.KeepPartial
+	if cfg.Attempts > 0 {
+		maxAttempts = cfg.Attempts
 	}
 
-	fmt.Fprintf(os.Stderr, "\nDownloading %s from:\n%s\n\n", opts, url)
+	// Ctrl+C outside the TUI (e.g. during the summary) cancels the run;
+	// being shut down or losing the terminal stops it to resume later
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, stopShutdown := withShutdown(ctx)
+	defer stopShutdown()
 
-	if err := runDownload(url, opts); err != nil {
+	err = dispatch(ctx, os.Args[1:])
+	switch {
+	case err == nil, errors.Is(err, errHelp):
+	case errors.Is(err, errUsage):
+		os.Exit(2)
+	case errors.Is(err, context.Canceled):
+		os.Exit(130)
+	default:
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	fmt.Fprintln(os.Stderr, "\n✓ Done!")
 }
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// NetworkConfig is how tuber reaches the internet, for proxies and
+// picky networks. It applies to yt-dlp and to the LLM backends.
+type NetworkConfig struct {
+	Proxy         string `json:"proxy,omitempty"`          // http://, https:// or socks5:// URL
+	SourceAddress string `json:"source_address,omitempty"` // local IP to connect from
+	ForceIPv4     bool   `json:"force_ipv4,omitempty"`
+	ForceIPv6     bool   `json:"force_ipv6,omitempty"`
+	UserAgent     string `json:"user_agent,omitempty"`
+}
+
+// check reports settings that can't work.
+func (n NetworkConfig) check() error {
+	if n.Proxy != "" {
+		u, err := url.Parse(n.Proxy)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("network.proxy %q should be a URL like http://proxy:3128 or socks5://localhost:1080", n.Proxy)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("network.proxy: unsupported scheme %q (use http, https or socks5)", u.Scheme)
+		}
+	}
+	if n.SourceAddress != "" && net.ParseIP(n.SourceAddress) == nil {
+		return fmt.Errorf("network.source_address %q should be an IP address", n.SourceAddress)
+	}
+	if n.ForceIPv4 && n.ForceIPv6 {
+		return errors.New("network: force_ipv4 and force_ipv6 can't both be set")
+	}
+	return nil
+}
+
+// ytdlpArgs returns the yt-dlp flags for these settings.
+func (n NetworkConfig) ytdlpArgs() []string {
+	var args []string
+	if n.Proxy != "" {
+		args = append(args, "--proxy", n.Proxy)
+	}
+	if n.SourceAddress != "" {
+		args = append(args, "--source-address", n.SourceAddress)
+	}
+	if n.ForceIPv4 {
+		args = append(args, "--force-ipv4")
+	}
+	if n.ForceIPv6 {
+		args = append(args, "--force-ipv6")
+	}
+	if n.UserAgent != "" {
+		args = append(args, "--add-headers", "User-Agent:"+n.UserAgent)
+	}
+	return args
+}
+
+// env returns environment variables that point a subprocess doing its
+// own HTTP, like the claude CLI, at the proxy.
+func (n NetworkConfig) env() []string {
+	if n.Proxy == "" {
+		return nil
+	}
+	return []string{"HTTPS_PROXY=" + n.Proxy, "HTTP_PROXY=" + n.Proxy, "ALL_PROXY=" + n.Proxy}
+}
+
+// httpClient returns a client that connects the way these settings say.
+// Without a proxy set, the usual $HTTPS_PROXY and friends still apply.
+func (n NetworkConfig) httpClient() *http.Client {
+	if n == (NetworkConfig{}) {
+		return http.DefaultClient
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if n.Proxy != "" {
+		if u, err := url.Parse(n.Proxy); err == nil {
+			transport.Proxy = http.ProxyURL(u)
+		}
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if n.SourceAddress != "" {
+		dialer.LocalAddr = &net.TCPAddr{IP: net.ParseIP(n.SourceAddress)}
+	}
+	network := ""
+	switch {
+	case n.ForceIPv4:
+		network = "tcp4"
+	case n.ForceIPv6:
+		network = "tcp6"
+	}
+	transport.DialContext = func(ctx context.Context, netw, addr string) (net.Conn, error) {
+		if network != "" {
+			netw = network
+		}
+		return dialer.DialContext(ctx, netw, addr)
+	}
+	return &http.Client{Transport: transport}
+}
+
+// addNetworkFlags registers flags that override the configured network
+// settings.
+func addNetworkFlags(fs *flag.FlagSet) {
+	fs.StringVar(&cfg.Network.Proxy, "proxy", cfg.Network.Proxy, "Connect through this proxy `url`, e.g. http://proxy:3128 or socks5://localhost:1080")
+	fs.StringVar(&cfg.Network.SourceAddress, "source-address", cfg.Network.SourceAddress, "Connect from this local `ip`")
+	fs.BoolFunc("force-ipv4", "Only connect over IPv4", forceIP(&cfg.Network.ForceIPv4, &cfg.Network.ForceIPv6))
+	fs.BoolFunc("force-ipv6", "Only connect over IPv6", forceIP(&cfg.Network.ForceIPv6, &cfg.Network.ForceIPv4))
+	fs.StringVar(&cfg.Network.UserAgent, "user-agent", cfg.Network.UserAgent, "Send this User-Agent")
+}
+
+// forceIP sets one IP version flag, clearing the other so the flag wins
+// over whichever the config forces.
+func forceIP(set, other *bool) func(string) error {
+	return func(s string) error {
+		v, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		*set = v
+		if v {
+			*other = false
+		}
+		return nil
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+)
+
+func TestNetworkFlagsOverrideConfig(t *testing.T) {
+	saved := cfg.Network
+	t.Cleanup(func() { cfg.Network = saved })
+
+	tests := []struct {
+		config     NetworkConfig
+		args       []string
+		ipv4, ipv6 bool
+	}{
+		{NetworkConfig{ForceIPv4: true}, []string{"-force-ipv6"}, false, true},
+		{NetworkConfig{ForceIPv6: true}, []string{"-force-ipv4"}, true, false},
+		{NetworkConfig{ForceIPv4: true}, []string{"-force-ipv4=false"}, false, false},
+		{NetworkConfig{}, []string{"-force-ipv4", "-force-ipv6"}, false, true},
+		{NetworkConfig{ForceIPv6: true}, nil, false, true},
+	}
+	for _, tt := range tests {
+		cfg.Network = tt.config
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		addNetworkFlags(fs)
+		if err := fs.Parse(tt.args); err != nil {
+			t.Fatalf("%v: %v", tt.args, err)
+		}
+		if err := cfg.Network.check(); err != nil {
+			t.Errorf("%+v with %v: %v", tt.config, tt.args, err)
+		}
+		if cfg.Network.ForceIPv4 != tt.ipv4 || cfg.Network.ForceIPv6 != tt.ipv6 {
+			t.Errorf("%+v with %v: force_ipv4 %v, force_ipv6 %v, want %v, %v",
+				tt.config, tt.args, cfg.Network.ForceIPv4, cfg.Network.ForceIPv6, tt.ipv4, tt.ipv6)
+		}
+	}
+}
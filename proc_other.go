@@ -0,0 +1,12 @@
+//go:build !unix
+
+package main
+
+import "os/exec"
+
+// setProcessGroup is a no-op where process groups aren't available; the
+// default exec.CommandContext behaviour of killing the process applies.
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// processAlive can't tell here, so assumes the process is gone.
+func processAlive(pid int) bool { return false }
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RateLimit paces requests to a site, so batches, playlists and tuber
+// watch don't get throttled.
+type RateLimit struct {
+	PerMinute int    `json:"per_minute,omitempty"` // yt-dlp runs started per minute, 0 for no limit
+	Sleep     string `json:"sleep,omitempty"`      // pause between videos in a batch, e.g. "10s"
+	LimitRate string `json:"limit_rate,omitempty"` // download bandwidth cap, as yt-dlp's --limit-rate, e.g. "2M"
+}
+
+// RateLimitConfig is the rate limit for every site, with overrides for
+// particular hosts.
+type RateLimitConfig struct {
+	RateLimit
+	Hosts map[string]RateLimit `json:"hosts,omitempty"` // by host, e.g. "youtube.com"; subdomains included
+}
+
+func (r RateLimit) sleep() time.Duration {
+	d, _ := time.ParseDuration(r.Sleep)
+	return d
+}
+
+// check reports a setting yt-dlp or tuber won't understand.
+func (r RateLimit) check() error {
+	if r.PerMinute < 0 {
+		return fmt.Errorf("per_minute should be 0 or more, not %d", r.PerMinute)
+	}
+	if r.Sleep != "" {
+		if d, err := time.ParseDuration(r.Sleep); err != nil || d < 0 {
+			return fmt.Errorf("sleep %q should be a duration like 5s or 1m", r.Sleep)
+		}
+	}
+	return nil
+}
+
+func (c RateLimitConfig) check() error {
+	if err := c.RateLimit.check(); err != nil {
+		return fmt.Errorf("rate_limit: %w", err)
+	}
+	for host, r := range c.Hosts {
+		if err := r.check(); err != nil {
+			return fmt.Errorf("rate_limit.hosts.%s: %w", host, err)
+		}
+	}
+	return nil
+}
+
+// forHost returns the limit for host: its own settings where it has
+// them, the global ones otherwise.
+func (c RateLimitConfig) forHost(host string) RateLimit {
+	r := c.RateLimit
+	host = strings.TrimPrefix(strings.ToLower(host), "www.")
+	for h, override := range c.Hosts {
+		h = strings.TrimPrefix(strings.ToLower(h), "www.")
+		if host != h && !strings.HasSuffix(host, "."+h) {
+			continue
+		}
+		if override.PerMinute != 0 {
+			r.PerMinute = override.PerMinute
+		}
+		if override.Sleep != "" {
+			r.Sleep = override.Sleep
+		}
+		if override.LimitRate != "" {
+			r.LimitRate = override.LimitRate
+		}
+	}
+	return r
+}
+
+// override changes a setting everywhere, hosts included, for flags,
+// which win over the config.
+func (c *RateLimitConfig) override(set func(*RateLimit)) {
+	set(&c.RateLimit)
+	for host, r := range c.Hosts {
+		set(&r)
+		c.Hosts[host] = r
+	}
+}
+
+// addRateFlags registers flags that override the configured rate limits.
+func addRateFlags(fs *flag.FlagSet) {
+	fs.Func("rate-limit", "Start at most `n` yt-dlp requests a minute per site (0 for no limit)", func(s string) error {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return errors.New("should be a number of requests, 0 or more")
+		}
+		cfg.RateLimit.override(func(r *RateLimit) { r.PerMinute = n })
+		return nil
+	})
+	fs.Func("sleep", "Pause this `long` between videos in a batch, e.g. 10s", func(s string) error {
+		if d, err := time.ParseDuration(s); err != nil || d < 0 {
+			return errors.New("should be a duration like 10s")
+		}
+		cfg.RateLimit.override(func(r *RateLimit) { r.Sleep = s })
+		return nil
+	})
+	fs.Func("limit-rate", "Cap download bandwidth at this `rate`, e.g. 2M, as yt-dlp's --limit-rate", func(s string) error {
+		cfg.RateLimit.override(func(r *RateLimit) { r.LimitRate = s })
+		return nil
+	})
+}
+
+// urlHost returns rawURL's host, as rate limits are looked up by.
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// rateLimiter spaces out requests to each host.
+type rateLimiter struct {
+	mu   sync.Mutex
+	next map[string]time.Time // when each host's next request may start
+}
+
+var limiter = &rateLimiter{next: make(map[string]time.Time)}
+
+// wait blocks until a request to host may start, at most perMinute a
+// minute, telling whoever's watching ctx (see withRateNotice) if it has
+// to wait.
+func (l *rateLimiter) wait(ctx context.Context, host string, perMinute int) error {
+	if perMinute <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	slot := now
+	if l.next[host].After(now) {
+		slot = l.next[host]
+	}
+	l.next[host] = slot.Add(time.Minute / time.Duration(perMinute))
+	l.mu.Unlock()
+
+	wait := slot.Sub(now)
+	if wait <= 0 {
+		return nil
+	}
+	notify := rateNotice(ctx)
+	notify(wait)
+	defer notify(0)
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(wait):
+		return nil
+	}
+}
+
+// waitTurn waits until a yt-dlp run fetching from rawURL may start under
+// the rate limit for its host.
+func waitTurn(ctx context.Context, rawURL string) error {
+	host := urlHost(rawURL)
+	return limiter.wait(ctx, host, cfg.RateLimit.forHost(host).PerMinute)
+}
+
+// pause sleeps between the videos of a batch, for as long as the rate
+// limit for rawURL's host says.
+func pause(ctx context.Context, rawURL string) error {
+	d := cfg.RateLimit.forHost(urlHost(rawURL)).sleep()
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+type rateNoticeKey struct{}
+
+// withRateNotice returns a ctx whose rate-limited requests call notify
+// with how long they're waiting, then with 0 once they're through.
+func withRateNotice(ctx context.Context, notify func(time.Duration)) context.Context {
+	return context.WithValue(ctx, rateNoticeKey{}, notify)
+}
+
+func rateNotice(ctx context.Context) func(time.Duration) {
+	if notify, ok := ctx.Value(rateNoticeKey{}).(func(time.Duration)); ok {
+		return notify
+	}
+	return func(time.Duration) {}
+}
+
+// rateStatus describes a wait on the rate limit for a progress line.
+func rateStatus(wait time.Duration) string {
+	return fmt.Sprintf("Waiting %s for the rate limit", wait.Round(time.Second))
+}
@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestRateLimitForHost(t *testing.T) {
+	c := RateLimitConfig{
+		RateLimit: RateLimit{PerMinute: 10, Sleep: "5s"},
+		Hosts: map[string]RateLimit{
+			"YouTube.com":     {PerMinute: 4, LimitRate: "2M"},
+			"www.example.com": {Sleep: "1m"},
+		},
+	}
+	tests := []struct {
+		url  string
+		want RateLimit
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", RateLimit{PerMinute: 4, Sleep: "5s", LimitRate: "2M"}},
+		{"https://music.youtube.com/watch?v=dQw4w9WgXcQ", RateLimit{PerMinute: 4, Sleep: "5s", LimitRate: "2M"}},
+		{"https://example.com/video", RateLimit{PerMinute: 10, Sleep: "1m"}},
+		{"https://notyoutube.com/video", RateLimit{PerMinute: 10, Sleep: "5s"}},
+		{"https://vimeo.com/123", RateLimit{PerMinute: 10, Sleep: "5s"}},
+	}
+	for _, tt := range tests {
+		if got := c.forHost(urlHost(tt.url)); got != tt.want {
+			t.Errorf("forHost(%s) = %+v, want %+v", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// A pendingRun is a download that's been started and hasn't finished.
+// It's saved before anything runs and after every step, so if tuber dies
+// part way through, tuber resume can pick up where it left off.
+type pendingRun struct {
+	ID        string          `json:"id"`
+	PID       int             `json:"pid"` // the tuber running it
+	Started   time.Time       `json:"started"`
+	URL       string          `json:"url"`
+	Title     string          `json:"title,omitempty"`
+	Channel   string          `json:"channel,omitempty"`
+	Duration  float64         `json:"duration,omitempty"`
+	Options   DownloadOptions `json:"options"`
+	OutputDir string          `json:"output_dir,omitempty"`
+	OutPath   string          `json:"out_path,omitempty"`
+	Steps     []string        `json:"steps"`
+	Completed []string        `json:"completed,omitempty"`
+	Files     []string        `json:"files,omitempty"`
+	Summary   string          `json:"summary,omitempty"`
+}
+
+// newPendingRun plans a run of opts on url, into the current output
+// settings. video is whatever's known about the video; it may be nil.
+func newPendingRun(url string, video *videoInfo, opts DownloadOptions) *pendingRun {
+	now := time.Now()
+	// The random part keeps runs started in the same millisecond, by tuber
+	// serve or another terminal, from sharing a state file
+	id := strconv.FormatInt(now.UnixMilli(), 36) + "-" + strconv.FormatInt(rand.Int64N(1<<31), 36)
+	p := &pendingRun{
+		ID:        id,
+		Started:   now,
+		URL:       url,
+		Options:   opts,
+		OutputDir: outputDir,
+		OutPath:   customOutPath,
+		Steps:     plannedSteps(opts),
+	}
+	if video != nil {
+		p.Title = video.Title
+		p.Channel = video.Channel
+		p.Duration = video.Duration
+	}
+	return p
+}
+
+func pendingDir() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "pending"), nil
+}
+
+// save writes the run's state file.
+func (p *pendingRun) save() error {
+	dir, err := pendingDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write then rename, so a crash never leaves half a file
+	path := filepath.Join(dir, p.ID+".json")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// remove deletes the run's state file, once there's nothing to resume.
+func (p *pendingRun) remove() {
+	if dir, err := pendingDir(); err == nil {
+		os.Remove(filepath.Join(dir, p.ID+".json"))
+	}
+}
+
+// stepDone records a finished step and saves the state.
+func (p *pendingRun) stepDone(step runResult) {
+	p.Completed = append(p.Completed, step.Completed...)
+	p.Files = append(p.Files, step.Files...)
+	if step.Summary != "" {
+		p.Summary = step.Summary
+	}
+	if err := p.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: couldn't save progress for resuming: %v\n", err)
+	}
+}
+
+// done returns what the run has got done so far.
+func (p *pendingRun) done() runResult {
+	return runResult{Completed: p.Completed, Files: p.Files, Summary: p.Summary}
+}
+
+// remaining returns the steps still to do.
+func (p *pendingRun) remaining() []string {
+	var steps []string
+	for _, step := range p.Steps {
+		if !slices.Contains(p.Completed, step) {
+			steps = append(steps, step)
+		}
+	}
+	return steps
+}
+
+// running reports whether the tuber that started the run is still going.
+func (p *pendingRun) running() bool {
+	return p.PID != os.Getpid() && processAlive(p.PID)
+}
+
+// loadPendingRuns returns every unfinished run, oldest first.
+func loadPendingRuns() ([]*pendingRun, error) {
+	dir, err := pendingDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var runs []*pendingRun
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var p pendingRun
+		if err := json.Unmarshal(data, &p); err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		runs = append(runs, &p)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].Started.Before(runs[j].Started) })
+	return runs, nil
+}
+
+type stepDoneKey struct{}
+
+// withStepDone returns a ctx whose runs call done with what each step
+// got done, as it finishes.
+func withStepDone(ctx context.Context, done func(step runResult)) context.Context {
+	return context.WithValue(ctx, stepDoneKey{}, done)
+}
+
+func stepDone(ctx context.Context) func(runResult) {
+	if done, ok := ctx.Value(stepDoneKey{}).(func(runResult)); ok {
+		return done
+	}
+	return func(runResult) {}
+}
+
+// errShutdown is why ctx is cancelled when tuber is being shut down or
+// has lost its terminal, rather than stopped by the user. A run cut short
+// that way keeps its partial files and is left for tuber resume.
+var errShutdown = errors.New("shutting down")
+
+// withShutdown returns a ctx that's cancelled with errShutdown on SIGTERM
+// or SIGHUP.
+func withShutdown(ctx context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGTERM, syscall.SIGHUP)
+	go func() {
+		select {
+		case <-sig:
+			cancel(errShutdown)
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(sig)
+		cancel(context.Canceled)
+	}
+}
+
+// shuttingDown reports whether ctx was cancelled by a shutdown.
+func shuttingDown(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errShutdown)
+}
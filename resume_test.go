@@ -0,0 +1,14 @@
+package main
+
+import "testing"
+
+func TestNewPendingRunIDsUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 100 {
+		p := newPendingRun("https://www.youtube.com/watch?v=dQw4w9WgXcQ", nil, DownloadOptions{Audio: true})
+		if seen[p.ID] {
+			t.Fatalf("newPendingRun() gave ID %s twice", p.ID)
+		}
+		seen[p.ID] = true
+	}
+}
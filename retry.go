@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxAttempts is how many times a step is tried before giving up.
+var maxAttempts = 4
+
+const (
+	retryBaseDelay = 2 * time.Second
+	retryMaxDelay  = 30 * time.Second
+)
+
+// ytdlpError carries what yt-dlp printed to stderr, so failures can be
+// reported and classified.
+type ytdlpError struct {
+	err    error
+	stderr string
+}
+
+func (e *ytdlpError) Error() string {
+	// yt-dlp puts the useful bit ("ERROR: ...") on the last line
+	lines := strings.Split(strings.TrimSpace(e.stderr), "\n")
+	if last := strings.TrimSpace(lines[len(lines)-1]); last != "" {
+		return last
+	}
+	return e.err.Error()
+}
+
+func (e *ytdlpError) Unwrap() error { return e.err }
+
+// ytdlpOutput runs yt-dlp, fetching from rawURL, and returns what it
+// printed to stdout. Stderr is captured into the error.
+func ytdlpOutput(ctx context.Context, rawURL string, args ...string) ([]byte, error) {
+	if err := waitTurn(ctx, rawURL); err != nil {
+		return nil, err
+	}
+	var stderr bytes.Buffer
+	cmd := ytdlpCommand(ctx, rawURL, args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, newYtdlpError(err, stderr.String())
+	}
+	return out, nil
+}
+
+var (
+	// Failures that won't go away by asking again
+	permanentFailure = regexp.MustCompile(`(?i)video unavailable|private video|not available|unsupported url|` +
+		`has been removed|copyright|sign in to confirm|members-only|join this channel|http error 40[0134]`)
+
+	// Network trouble, server errors and throttling
+	transientFailure = regexp.MustCompile(`(?i)http error (5\d\d|429)|too many requests|timed out|timeout|` +
+		`connection (reset|refused|aborted)|remote end closed|temporary failure|name resolution|` +
+		`network is unreachable|incompleteread|urlopen error|unable to download|got error|eof occurred`)
+)
+
+// isRetryable reports whether err looks like a transient failure worth
+// trying again.
+func isRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var yerr *ytdlpError
+	if !errors.As(err, &yerr) {
+		return false
+	}
+	if permanentFailure.MatchString(yerr.stderr) {
+		return false
+	}
+	return transientFailure.MatchString(yerr.stderr)
+}
+
+// retryDelay returns the backoff before the given attempt (2 = first retry),
+// doubling each time up to retryMaxDelay, with jitter so parallel runs
+// don't retry in lockstep.
+func retryDelay(attempt int) time.Duration {
+	d := retryBaseDelay << (attempt - 2)
+	if d <= 0 || d > retryMaxDelay {
+		d = retryMaxDelay
+	}
+	// Somewhere between half and the full delay
+	return d/2 + rand.N(d/2+1)
+}
+
+// withRetry runs fn until it succeeds, fails permanently, or runs out of
+// attempts. onRetry, if set, is called before each wait.
+func withRetry(ctx context.Context, fn func() error, onRetry func(attempt int, delay time.Duration, err error)) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= maxAttempts || !isRetryable(err) {
+			return err
+		}
+
+		delay := retryDelay(attempt + 1)
+		if onRetry != nil {
+			onRetry(attempt+1, delay, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestIsRetryable(t *testing.T) {
+	exit := errors.New("exit status 1")
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"canceled", context.Canceled, false},
+		{"deadline", fmt.Errorf("step: %w", context.DeadlineExceeded), false},
+		{"not from yt-dlp", errors.New("HTTP Error 503"), false},
+		{"server error", &ytdlpError{exit, "ERROR: unable to download video data: HTTP Error 503: Service Unavailable"}, true},
+		{"throttled", &ytdlpError{exit, "ERROR: HTTP Error 429: Too Many Requests"}, true},
+		{"connection reset", &ytdlpError{exit, "ERROR: [Errno 104] Connection reset by peer"}, true},
+		{"timed out", &ytdlpError{exit, "ERROR: Read timed out."}, true},
+		{"wrapped", fmt.Errorf("download: %w", &ytdlpError{exit, "ERROR: Remote end closed connection"}), true},
+		{"not found", &ytdlpError{exit, "ERROR: unable to download webpage: HTTP Error 404: Not Found"}, false},
+		{"private", &ytdlpError{exit, "ERROR: [youtube] abc: Private video"}, false},
+		{"members-only", &ytdlpError{exit, "ERROR: Join this channel to get access to members-only content"}, false},
+		{"unknown", &ytdlpError{exit, "ERROR: something else"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempt int
+		full    time.Duration
+	}{
+		{2, retryBaseDelay},
+		{3, 2 * retryBaseDelay},
+		{4, 4 * retryBaseDelay},
+		{6, retryMaxDelay},
+		{100, retryMaxDelay},
+	}
+	for _, tt := range tests {
+		for range 50 {
+			got := retryDelay(tt.attempt)
+			if got < tt.full/2 || got > tt.full {
+				t.Errorf("retryDelay(%d) = %v, want between %v and %v", tt.attempt, got, tt.full/2, tt.full)
+				break
+			}
+		}
+	}
+}
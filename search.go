@@ -0,0 +1,453 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// A searchDoc is one transcript in the search index.
+type searchDoc struct {
+	Path    string // the .txt transcript, absolute
+	URL     string // the video, if known
+	Title   string
+	Indexed time.Time
+	Lines   []searchLine
+	Terms   map[string][]int // term -> the lines it's on
+}
+
+// A searchLine is one line of a transcript.
+type searchLine struct {
+	Start float64 // seconds, or -1 for transcripts indexed without their subtitles
+	Text  string
+}
+
+// searchIndex is an inverted index over every transcript tuber has
+// written. Each transcript is kept in its own file in the index directory
+// next to the config, so adding one is a single small write, and tuber
+// serve and a download running at once can't lose each other's.
+type searchIndex struct {
+	Docs map[string]*searchDoc // by key (see searchDoc.key)
+}
+
+func indexDir() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "index"), nil
+}
+
+// key identifies a transcript: its video's URL, or its path if there
+// isn't one.
+func (doc *searchDoc) key() string {
+	return cmp.Or(doc.URL, doc.Path)
+}
+
+// file returns where a transcript is kept in the index directory.
+func (doc *searchDoc) file(dir string) string {
+	sum := sha256.Sum256([]byte(doc.key()))
+	return filepath.Join(dir, hex.EncodeToString(sum[:12])+".gob")
+}
+
+// loadIndex reads the search index. A missing index is an empty one.
+func loadIndex() (*searchIndex, error) {
+	idx := &searchIndex{Docs: make(map[string]*searchDoc)}
+	dir, err := indexDir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return idx, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".gob") {
+			continue
+		}
+		doc, err := readDoc(filepath.Join(dir, entry.Name()))
+		if errors.Is(err, os.ErrNotExist) {
+			// Replaced or dropped meanwhile
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w (rebuild the index with tuber search -reindex)", entry.Name(), err)
+		}
+		idx.Docs[doc.key()] = doc
+	}
+	return idx, nil
+}
+
+func readDoc(path string) (*searchDoc, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var doc searchDoc
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// save writes a transcript into the index, replacing any earlier copy of
+// the same video.
+func (doc *searchDoc) save() error {
+	dir, err := indexDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// Write then rename, so searches never see half a transcript
+	f, err := os.CreateTemp(dir, "*.tmp")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	err = gob.NewEncoder(w).Encode(doc)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), doc.file(dir))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// remove drops a transcript from the index.
+func (doc *searchDoc) remove() error {
+	dir, err := indexDir()
+	if err != nil {
+		return err
+	}
+	return os.Remove(doc.file(dir))
+}
+
+// index builds the transcript's terms, ready to save.
+func (doc *searchDoc) index() {
+	doc.Indexed = time.Now()
+	doc.Terms = make(map[string][]int)
+	for i, line := range doc.Lines {
+		for _, term := range slices.Compact(slices.Sorted(slices.Values(searchTerms(line.Text)))) {
+			doc.Terms[term] = append(doc.Terms[term], i)
+		}
+	}
+}
+
+// searchTerms splits text into lowercase words.
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '\''
+	})
+}
+
+// indexTranscript adds a transcript tuber has just written to the index.
+// Failing to is only worth a warning; the download itself worked.
+func indexTranscript(url, txtPath string, cues []cue) {
+	abs, err := filepath.Abs(txtPath)
+	if err != nil {
+		abs = txtPath
+	}
+	doc := &searchDoc{Path: abs, URL: url, Title: transcriptTitle(txtPath)}
+	for _, c := range cues {
+		doc.Lines = append(doc.Lines, searchLine{Start: c.Start, Text: c.Text})
+	}
+	doc.index()
+	if err := doc.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: couldn't add the transcript to the search index: %v\n", err)
+	}
+}
+
+// subtitleLang matches the language yt-dlp puts before the extension,
+// e.g. the ".en" in "Title.en.txt".
+var subtitleLang = regexp.MustCompile(`\.[a-z]{2,3}(-[A-Za-z]+)?$`)
+
+// transcriptTitle guesses a video's title from its transcript's filename.
+func transcriptTitle(path string) string {
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return subtitleLang.ReplaceAllString(base, "")
+}
+
+// reindex brings the index up to date with the transcripts on disk:
+// anything in the history or in dirs that isn't indexed yet is added, and
+// transcripts that have been deleted are dropped. Transcripts indexed from
+// disk have no timestamps, since the subtitles they came from are gone.
+func reindex(dirs []string) (added, removed int, err error) {
+	idx, err := loadIndex()
+	if err != nil {
+		// Start again rather than leave a broken index stuck
+		dir, derr := indexDir()
+		if derr != nil {
+			return 0, 0, derr
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return 0, 0, err
+		}
+		idx = &searchIndex{Docs: make(map[string]*searchDoc)}
+	}
+	for key, doc := range idx.Docs {
+		if _, err := os.Stat(doc.Path); err != nil {
+			if err := doc.remove(); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return added, removed, err
+			}
+			delete(idx.Docs, key)
+			removed++
+		}
+	}
+	indexed := make(map[string]bool)
+	for _, doc := range idx.Docs {
+		indexed[doc.Path] = true
+	}
+
+	// The history knows which video each transcript came from
+	history, err := loadHistory()
+	if err != nil {
+		return 0, 0, err
+	}
+	urls := make(map[string]string)
+	var paths []string
+	for _, e := range history {
+		for _, f := range e.Files {
+			if strings.HasSuffix(f, ".txt") && !strings.HasSuffix(f, ".chapters.txt") {
+				urls[filepath.Base(f)] = e.URL
+				paths = append(paths, f)
+			}
+		}
+	}
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			// Only transcripts tuber wrote, which have a language suffix
+			if !entry.IsDir() && strings.HasSuffix(name, ".txt") && !strings.HasSuffix(name, ".chapters.txt") &&
+				subtitleLang.MatchString(strings.TrimSuffix(name, ".txt")) {
+				paths = append(paths, filepath.Join(dir, name))
+			}
+		}
+	}
+
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil || indexed[abs] {
+			continue
+		}
+		data, err := os.ReadFile(abs)
+		if err != nil {
+			continue
+		}
+		doc := &searchDoc{Path: abs, URL: urls[filepath.Base(abs)], Title: transcriptTitle(abs)}
+		if doc.URL != "" && idx.Docs[doc.URL] != nil {
+			// Indexed from another copy, with timestamps
+			continue
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				doc.Lines = append(doc.Lines, searchLine{Start: -1, Text: line})
+			}
+		}
+		doc.index()
+		if err := doc.save(); err != nil {
+			return added, removed, err
+		}
+		idx.Docs[doc.key()] = doc
+		indexed[abs] = true
+		added++
+	}
+	return added, removed, nil
+}
+
+// A searchQuery is what was searched for: words that must all appear in
+// a transcript, and "quoted phrases" that must appear on one line.
+type searchQuery struct {
+	Terms   []string
+	Phrases []string
+}
+
+var quotedPhrase = regexp.MustCompile(`"([^"]*)"`)
+
+func parseQuery(q string) searchQuery {
+	var query searchQuery
+	for _, m := range quotedPhrase.FindAllStringSubmatch(q, -1) {
+		if words := searchTerms(m[1]); len(words) > 0 {
+			query.Phrases = append(query.Phrases, strings.Join(words, " "))
+			query.Terms = append(query.Terms, words...)
+		}
+	}
+	query.Terms = append(query.Terms, searchTerms(quotedPhrase.ReplaceAllString(q, " "))...)
+	slices.Sort(query.Terms)
+	query.Terms = slices.Compact(query.Terms)
+	return query
+}
+
+// A searchHit is one transcript that matched, with its best lines.
+type searchHit struct {
+	Title string          `json:"title"`
+	URL   string          `json:"url,omitempty"`
+	Path  string          `json:"path"`
+	Score float64         `json:"score"`
+	Lines []searchHitLine `json:"lines"`
+}
+
+type searchHitLine struct {
+	Start float64 `json:"start"` // -1 if unknown
+	Text  string  `json:"text"`
+	Link  string  `json:"link,omitempty"` // to this moment in the video
+}
+
+// search returns the transcripts with every term and phrase in query,
+// best first, scored by tf-idf. Each comes with up to maxLines of its
+// lines, the ones matching the most terms, in the order they're said.
+func (idx *searchIndex) search(query searchQuery, maxLines int) []searchHit {
+	if len(query.Terms) == 0 {
+		return nil
+	}
+	df := make(map[string]int)
+	for _, doc := range idx.Docs {
+		for _, term := range query.Terms {
+			if len(doc.Terms[term]) > 0 {
+				df[term]++
+			}
+		}
+	}
+
+	var hits []searchHit
+	for _, doc := range idx.Docs {
+		matched := make(map[int]int) // line -> how many terms are on it
+		score := 0.0
+		for _, term := range query.Terms {
+			lines := doc.Terms[term]
+			if len(lines) == 0 {
+				score = 0
+				break
+			}
+			score += float64(len(lines)) * math.Log(1+float64(len(idx.Docs))/float64(df[term]))
+			for _, i := range lines {
+				matched[i]++
+			}
+		}
+		if score == 0 {
+			continue
+		}
+		// Phrases have to be on a line, and lines with one count most
+		phraseOK := true
+		for _, phrase := range query.Phrases {
+			found := false
+			for i := range matched {
+				if strings.Contains(" "+strings.Join(searchTerms(doc.Lines[i].Text), " ")+" ", " "+phrase+" ") {
+					matched[i] += len(query.Terms)
+					found = true
+				}
+			}
+			phraseOK = phraseOK && found
+		}
+		if !phraseOK {
+			continue
+		}
+
+		best := slices.Collect(maps.Keys(matched))
+		slices.SortFunc(best, func(a, b int) int {
+			return cmp.Or(matched[b]-matched[a], a-b)
+		})
+		best = best[:min(maxLines, len(best))]
+		slices.Sort(best)
+
+		hit := searchHit{Title: doc.Title, URL: doc.URL, Path: doc.Path, Score: score}
+		id := youtubeID(doc.URL)
+		for _, i := range best {
+			line := searchHitLine{Start: doc.Lines[i].Start, Text: doc.Lines[i].Text, Link: doc.URL}
+			if id != "" && line.Start >= 0 {
+				line.Link = fmt.Sprintf("https://youtu.be/%s?t=%d", id, int(line.Start))
+			}
+			hit.Lines = append(hit.Lines, line)
+		}
+		hits = append(hits, hit)
+	}
+	slices.SortFunc(hits, func(a, b searchHit) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), strings.Compare(a.Title, b.Title))
+	})
+	return hits
+}
+
+var matchStyle = lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("212"))
+
+// printHits writes search results for reading, with the query's words
+// highlighted.
+func printHits(w io.Writer, hits []searchHit, query searchQuery) {
+	words := make(map[string]bool)
+	for _, term := range query.Terms {
+		words[term] = true
+	}
+	highlight := func(text string) string {
+		var b strings.Builder
+		word := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '\'' }
+		for len(text) > 0 {
+			i := strings.IndexFunc(text, word)
+			if i < 0 {
+				b.WriteString(text)
+				break
+			}
+			b.WriteString(text[:i])
+			text = text[i:]
+			j := strings.IndexFunc(text, func(r rune) bool { return !word(r) })
+			if j < 0 {
+				j = len(text)
+			}
+			if words[strings.ToLower(text[:j])] {
+				b.WriteString(matchStyle.Render(text[:j]))
+			} else {
+				b.WriteString(text[:j])
+			}
+			text = text[j:]
+		}
+		return b.String()
+	}
+
+	for i, hit := range hits {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w, titleStyle.Render(hit.Title))
+		for _, line := range hit.Lines {
+			when := "     "
+			if line.Start >= 0 {
+				when = fmt.Sprintf("%5s", formatDuration(line.Start))
+			}
+			fmt.Fprintf(w, "  %s  %s", dimStyle.Render(when), highlight(line.Text))
+			if line.Link != "" {
+				fmt.Fprintf(w, "  %s", dimStyle.Render(line.Link))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+}
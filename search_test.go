@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		in   string
+		want searchQuery
+	}{
+		{"Rust borrow", searchQuery{Terms: []string{"borrow", "rust"}}},
+		{`"Borrow Checker" rust`, searchQuery{Terms: []string{"borrow", "checker", "rust"}, Phrases: []string{"borrow checker"}}},
+		{`it's, don't! rust rust`, searchQuery{Terms: []string{"don't", "it's", "rust"}}},
+		{`"" ?!`, searchQuery{}},
+	}
+	for _, tt := range tests {
+		if got := parseQuery(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseQuery(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	idx := &searchIndex{Docs: make(map[string]*searchDoc)}
+	for _, doc := range []*searchDoc{
+		{URL: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", Title: "Borrowing", Lines: []searchLine{
+			{0, "Today we look at the borrow checker"},
+			{12.5, "The checker rejects this"},
+			{30, "Borrow rules in Rust"},
+		}},
+		{Path: "/out/other.txt", Title: "Other", Lines: []searchLine{
+			{-1, "Checker boards and a borrow of sugar"},
+		}},
+		{Path: "/out/unrelated.txt", Title: "Unrelated", Lines: []searchLine{
+			{-1, "Nothing to see here"},
+		}},
+	} {
+		doc.index()
+		idx.Docs[doc.key()] = doc
+	}
+
+	titles := func(hits []searchHit) []string {
+		var out []string
+		for _, h := range hits {
+			out = append(out, h.Title)
+		}
+		return out
+	}
+
+	hits := idx.search(parseQuery("borrow checker"), 2)
+	if got, want := titles(hits), []string{"Borrowing", "Other"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("search(borrow checker) = %v, want %v", got, want)
+	}
+	wantLines := []searchHitLine{
+		{Start: 0, Text: "Today we look at the borrow checker", Link: "https://youtu.be/dQw4w9WgXcQ?t=0"},
+		{Start: 12.5, Text: "The checker rejects this", Link: "https://youtu.be/dQw4w9WgXcQ?t=12"},
+	}
+	if !reflect.DeepEqual(hits[0].Lines, wantLines) {
+		t.Errorf("search(borrow checker) lines = %+v, want %+v", hits[0].Lines, wantLines)
+	}
+
+	// The phrase is only on a line of the first
+	if got, want := titles(idx.search(parseQuery(`"borrow checker"`), 3)), []string{"Borrowing"}; !reflect.DeepEqual(got, want) {
+		t.Errorf(`search("borrow checker") = %v, want %v`, got, want)
+	}
+	if got := idx.search(parseQuery("borrow nothing"), 3); len(got) != 0 {
+		t.Errorf("search(borrow nothing) = %v, want no hits", titles(got))
+	}
+}
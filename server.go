@@ -0,0 +1,835 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Job states, past the first two the same as history outcomes
+const (
+	jobQueued    = "queued"
+	jobRunning   = "running"
+	jobDone      = outcomeDone
+	jobFailed    = outcomeFailed
+	jobCancelled = outcomeCancelled
+)
+
+// A job is a download submitted to tuber serve.
+type job struct {
+	ID        int             `json:"id"`
+	URL       string          `json:"url"`
+	Options   DownloadOptions `json:"options"`
+	OutputDir string          `json:"output_dir,omitempty"`
+	OutPath   string          `json:"out_path,omitempty"` // dir + basename, like the menu's path
+	Title     string          `json:"title,omitempty"`
+	State     string          `json:"state"`
+	Progress  string          `json:"progress,omitempty"` // what it's doing right now
+	Waiting   string          `json:"waiting,omitempty"`  // set while it's held up by the rate limit
+	Completed []string        `json:"completed,omitempty"`
+	Files     []string        `json:"files,omitempty"`
+	Summary   string          `json:"summary,omitempty"`
+	Error     string          `json:"error,omitempty"`
+	Created   time.Time       `json:"created"`
+	Started   time.Time       `json:"started,omitzero"`
+	Finished  time.Time       `json:"finished,omitzero"`
+
+	rev int // the queue's rev when this job last changed
+}
+
+// jobQueue holds the server's jobs and runs them in order. Jobs run one
+// at a time, since the output settings they need are globals. The queue
+// is saved to jobs.json whenever a job changes state or finishes a step,
+// so it survives restarts; what a job's doing right now isn't saved.
+type jobQueue struct {
+	mu     sync.Mutex
+	path   string
+	jobs   []*job
+	rev    int // counts changes, so watchers can tell what's new
+	wake   chan struct{}
+	cancel map[int]context.CancelFunc // for the running job
+	subs   map[chan struct{}]bool     // watchers, poked on every change
+}
+
+func jobsPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "jobs.json"), nil
+}
+
+// finishedJobsKept is how long finished jobs stay in the queue, and so in
+// jobs.json and the web UI. The history keeps them for good.
+const finishedJobsKept = 7 * 24 * time.Hour
+
+// loadJobQueue reads the saved queue. Jobs that were running when the
+// server stopped are queued again.
+func loadJobQueue() (*jobQueue, error) {
+	path, err := jobsPath()
+	if err != nil {
+		return nil, err
+	}
+	q := &jobQueue{
+		path:   path,
+		wake:   make(chan struct{}, 1),
+		cancel: make(map[int]context.CancelFunc),
+		subs:   make(map[chan struct{}]bool),
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return q, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &q.jobs); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for _, j := range q.jobs {
+		if j.State == jobRunning {
+			j.State = jobQueued
+			j.Progress = ""
+		}
+		q.rev++
+		j.rev = q.rev
+	}
+	q.prune()
+	return q, nil
+}
+
+// changed records that j has changed and tells watchers, saving the queue
+// too if save is set. q.mu must be held.
+func (q *jobQueue) changed(j *job, save bool) {
+	q.rev++
+	j.rev = q.rev
+	if save {
+		q.save()
+	}
+	for sub := range q.subs {
+		select {
+		case sub <- struct{}{}:
+		default:
+			// Already poked, and it'll see this change too
+		}
+	}
+}
+
+// prune drops jobs that finished more than finishedJobsKept ago. The
+// newest job always stays, since the next ID follows on from it. q.mu must
+// be held.
+func (q *jobQueue) prune() {
+	cutoff := time.Now().Add(-finishedJobsKept)
+	kept := q.jobs[:0]
+	for i, j := range q.jobs {
+		if i == len(q.jobs)-1 || j.Finished.IsZero() || j.Finished.After(cutoff) {
+			kept = append(kept, j)
+		}
+	}
+	clear(q.jobs[len(kept):])
+	q.jobs = kept
+}
+
+// save prunes the queue and writes it out. q.mu must be held.
+func (q *jobQueue) save() {
+	q.prune()
+	data, err := json.MarshalIndent(q.jobs, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(q.path), 0755)
+	}
+	if err == nil {
+		// Write then rename, so a crash never leaves half a file
+		tmp := q.path + ".tmp"
+		if err = os.WriteFile(tmp, append(data, '\n'), 0644); err == nil {
+			err = os.Rename(tmp, q.path)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: couldn't save jobs: %v\n", err)
+	}
+}
+
+// add queues a new job, returning it with its ID.
+func (q *jobQueue) add(j job) job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j.ID = 1
+	if len(q.jobs) > 0 {
+		j.ID = q.jobs[len(q.jobs)-1].ID + 1
+	}
+	j.State = jobQueued
+	j.Created = time.Now()
+	q.jobs = append(q.jobs, &j)
+	q.changed(&j, true)
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return j
+}
+
+// list returns every job, newest first.
+func (q *jobQueue) list() []job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := make([]job, 0, len(q.jobs))
+	for _, j := range slices.Backward(q.jobs) {
+		jobs = append(jobs, *j)
+	}
+	return jobs
+}
+
+// watch returns a channel that's poked whenever a job changes, and a
+// func to stop watching.
+func (q *jobQueue) watch() (<-chan struct{}, func()) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	sub := make(chan struct{}, 1)
+	q.subs[sub] = true
+	return sub, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		delete(q.subs, sub)
+	}
+}
+
+// since returns the jobs that have changed after rev, oldest change
+// first, and the rev they bring a watcher up to.
+func (q *jobQueue) since(rev int) ([]job, int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var jobs []job
+	for _, j := range q.jobs {
+		if j.rev > rev {
+			jobs = append(jobs, *j)
+		}
+	}
+	slices.SortFunc(jobs, func(a, b job) int { return a.rev - b.rev })
+	return jobs, q.rev
+}
+
+func (q *jobQueue) get(id int) (job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, j := range q.jobs {
+		if j.ID == id {
+			return *j, true
+		}
+	}
+	return job{}, false
+}
+
+// update applies fn to a job and saves the queue.
+func (q *jobQueue) update(id int, fn func(*job)) {
+	q.apply(id, fn, true)
+}
+
+// report applies fn to a job without saving the queue, for its Progress
+// and Waiting, which change too often to be worth saving and mean nothing
+// after a restart.
+func (q *jobQueue) report(id int, fn func(*job)) {
+	q.apply(id, fn, false)
+}
+
+func (q *jobQueue) apply(id int, fn func(*job), save bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, j := range q.jobs {
+		if j.ID == id {
+			fn(j)
+			q.changed(j, save)
+			return
+		}
+	}
+}
+
+// cancelJob stops a running job, or drops a queued one.
+func (q *jobQueue) cancelJob(id int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, j := range q.jobs {
+		if j.ID != id {
+			continue
+		}
+		switch j.State {
+		case jobQueued:
+			j.State = jobCancelled
+			j.Finished = time.Now()
+			q.changed(j, true)
+		case jobRunning:
+			// runJob records the outcome once it's stopped
+			j.Progress = "Cancelling"
+			q.cancel[id]()
+		default:
+			return fmt.Errorf("job %d is already %s", id, j.State)
+		}
+		return nil
+	}
+	return fmt.Errorf("no job %d", id)
+}
+
+// next waits for a queued job, returning false when ctx is done.
+func (q *jobQueue) next(ctx context.Context) (job, bool) {
+	for {
+		// A job cut short by the server stopping is queued again, so
+		// don't pick it straight back up
+		if ctx.Err() != nil {
+			return job{}, false
+		}
+		q.mu.Lock()
+		for _, j := range q.jobs {
+			if j.State == jobQueued {
+				q.mu.Unlock()
+				return *j, true
+			}
+		}
+		q.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return job{}, false
+		case <-q.wake:
+		}
+	}
+}
+
+// work runs queued jobs until ctx is done, pausing between them as the
+// rate limit says.
+func (q *jobQueue) work(ctx context.Context) {
+	ran := false
+	for {
+		j, ok := q.next(ctx)
+		if !ok {
+			return
+		}
+		if ran {
+			if d := cfg.RateLimit.forHost(urlHost(j.URL)).sleep(); d > 0 {
+				q.report(j.ID, func(j *job) { j.Waiting = fmt.Sprintf("Pausing %s between videos", d) })
+				err := pause(ctx, j.URL)
+				q.report(j.ID, func(j *job) { j.Waiting = "" })
+				if err != nil {
+					return
+				}
+				// It may have been cancelled meanwhile
+				if j, _ = q.get(j.ID); j.State != jobQueued {
+					continue
+				}
+			}
+		}
+		q.runJob(ctx, j)
+		ran = true
+	}
+}
+
+// runJob runs one job through the same pipeline as tuber get, recording
+// it in the history too. If the server is stopping, the job goes back in
+// the queue for next time.
+func (q *jobQueue) runJob(ctx context.Context, j job) {
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	jobCtx = withRateNotice(jobCtx, func(wait time.Duration) {
+		q.report(j.ID, func(j *job) {
+			j.Waiting = ""
+			if wait > 0 {
+				j.Waiting = rateStatus(wait)
+			}
+		})
+	})
+	started := time.Now()
+	// Registering the cancel func along with the state change means
+	// cancelJob never sees a running job it can't stop
+	cancelled := false
+	q.update(j.ID, func(j *job) {
+		if j.State != jobQueued {
+			// Cancelled since it was picked off the queue
+			cancelled = true
+			return
+		}
+		j.State = jobRunning
+		j.Started = started
+		j.Progress = "Fetching video info"
+		q.cancel[j.ID] = cancel
+	})
+	if cancelled {
+		return
+	}
+	defer func() {
+		q.mu.Lock()
+		delete(q.cancel, j.ID)
+		q.mu.Unlock()
+	}()
+	fmt.Fprintf(os.Stderr, "▶ Job %d: %s from %s\n", j.ID, j.Options, j.URL)
+
+	info, err := fetchInfo(jobCtx, j.URL)
+	if err == nil {
+		q.update(j.ID, func(j *job) { j.Title = info.Title })
+	}
+
+	outputDir = cmp.Or(j.OutputDir, cfg.OutputDir)
+	customOutPath = j.OutPath
+	// Steps are saved as they finish, so a job requeued by a shutdown or
+	// crash carries on from where it was
+	jobCtx = withStepDone(jobCtx, func(step runResult) {
+		q.update(j.ID, func(j *job) {
+			j.Completed = append(j.Completed, step.Completed...)
+			j.Files = append(j.Files, step.Files...)
+			j.Summary = cmp.Or(step.Summary, j.Summary)
+		})
+	})
+	done := runResult{Completed: j.Completed, Files: j.Files, Summary: j.Summary}
+	res, err := runDownload(jobCtx, j.URL, j.Options, done, func(status string) {
+		q.report(j.ID, func(j *job) { j.Progress = status })
+	})
+
+	if ctx.Err() != nil {
+		q.update(j.ID, func(j *job) {
+			j.State = jobQueued
+			j.Progress = ""
+		})
+		return
+	}
+	entry := recordRun(started, j.URL, info, j.Options, res, err)
+	q.update(j.ID, func(j *job) {
+		j.State = entry.Outcome
+		j.Title = entry.Title
+		j.Progress = ""
+		j.Completed = res.Completed
+		j.Files = res.Files
+		j.Summary = res.Summary
+		j.Error = entry.Error
+		j.Finished = time.Now()
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ Job %d %s: %v\n", j.ID, entry.Outcome, err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "✓ Job %d done\n", j.ID)
+}
+
+// A jobRequest is the body of POST /api/jobs.
+type jobRequest struct {
+	URL       string          `json:"url"`
+	Options   DownloadOptions `json:"options"`
+	Preset    string          `json:"preset,omitempty"` // summary preset, instead of options.prompt
+	OutputDir string          `json:"output_dir,omitempty"`
+	OutPath   string          `json:"out_path,omitempty"`
+}
+
+// newJob checks a request and turns it into a job. Its output paths have
+// to be inside the configured output directory, so a request can't write
+// just anywhere.
+func newJob(req jobRequest) (job, error) {
+	url, err := normalizeURL(req.URL)
+	if err != nil {
+		return job{}, err
+	}
+	opts := req.Options
+	if opts.isEmpty() {
+		return job{}, errors.New("pick something to do: video, audio, subs, summary or chapters")
+	}
+	if opts.Summary || opts.Chapters {
+		if err := requireLLM(); err != nil {
+			return job{}, err
+		}
+	}
+	if req.Preset != "" {
+		if opts.Prompt, err = cfg.preset(req.Preset); err != nil {
+			return job{}, err
+		}
+	}
+	if opts.Summary && opts.Prompt == "" {
+		opts.Prompt = cfg.prompt()
+	}
+	outDir, err := confinePath(req.OutputDir)
+	if err != nil {
+		return job{}, err
+	}
+	outPath, err := confinePath(req.OutPath)
+	if err != nil {
+		return job{}, err
+	}
+	return job{URL: url, Options: opts, OutputDir: outDir, OutPath: outPath}, nil
+}
+
+// confinePath resolves a path from a request against the output
+// directory, refusing one that ends up outside it.
+func confinePath(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	base, err := filepath.Abs(cmp.Or(cfg.OutputDir, "."))
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(base, path)
+	}
+	path = filepath.Clean(path)
+	rel, err := filepath.Rel(base, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the output directory %s", path, base)
+	}
+	return path, nil
+}
+
+// server is tuber serve's HTTP API.
+type server struct {
+	queue       *jobQueue
+	allowOrigin string // for CORS, e.g. a bookmarklet running on youtube.com
+	token       string // needed on every request, if set
+	loopback    bool   // listening only on this machine
+}
+
+func (s *server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/jobs", s.handleSubmit)
+	mux.HandleFunc("GET /api/jobs", s.handleList)
+	mux.HandleFunc("GET /api/jobs/{id}", s.handleJob)
+	mux.HandleFunc("POST /api/jobs/{id}/cancel", s.handleCancel)
+	mux.HandleFunc("GET /api/jobs/{id}/summary", s.handleSummary)
+	mux.HandleFunc("GET /api/jobs/{id}/files/{name}", s.handleFile)
+	mux.HandleFunc("GET /api/info", s.handleInfo)
+	mux.HandleFunc("GET /api/presets", s.handlePresets)
+	mux.HandleFunc("GET /api/events", s.handleEvents)
+	mux.Handle("GET /", webUI())
+
+	// Browsers mustn't let other sites' pages drive the API: they could
+	// queue downloads, write files and send prompts to the LLM
+	csrf := http.NewCrossOriginProtection()
+	if s.allowOrigin != "" {
+		csrf.AddTrustedOrigin(s.allowOrigin)
+	}
+	return s.cors(s.guard(csrf.Handler(mux)))
+}
+
+// tokenCookie keeps the web UI signed in once it's been opened with
+// ?token=.
+const tokenCookie = "tuber_token"
+
+// guard turns away requests that aren't for this server or aren't
+// allowed to use it.
+func (s *server) guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Listening on loopback, any other Host is a page whose name was
+		// pointed at 127.0.0.1 to get around the same-origin checks
+		if s.loopback && !isLoopbackHost(r.Host) {
+			writeError(w, http.StatusMisdirectedRequest, fmt.Errorf("unexpected host %q", r.Host))
+			return
+		}
+		// GETs aren't covered by the cross-origin protection, but
+		// /api/info fetches whatever URL it's given
+		if site := r.Header.Get("Sec-Fetch-Site"); (site == "cross-site" || site == "same-site") &&
+			strings.HasPrefix(r.URL.Path, "/api/") && (s.allowOrigin == "" || r.Header.Get("Origin") != s.allowOrigin) {
+			writeError(w, http.StatusForbidden, errors.New("cross-origin requests aren't allowed"))
+			return
+		}
+		if s.token != "" {
+			if t := r.URL.Query().Get("token"); t != "" && s.validToken(t) {
+				http.SetCookie(w, &http.Cookie{Name: tokenCookie, Value: t, Path: "/", HttpOnly: true, SameSite: http.SameSiteStrictMode})
+			} else if !s.authorized(r) {
+				writeError(w, http.StatusUnauthorized, errors.New("this needs the server's token: an Authorization: Bearer header, or ?token= in the web UI's address"))
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *server) validToken(t string) bool {
+	return subtle.ConstantTimeCompare([]byte(t), []byte(s.token)) == 1
+}
+
+// authorized reports whether r carries the token, as a header or the
+// web UI's cookie.
+func (s *server) authorized(r *http.Request) bool {
+	if t, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && s.validToken(t) {
+		return true
+	}
+	c, err := r.Cookie(tokenCookie)
+	return err == nil && s.validToken(c.Value)
+}
+
+// isLoopbackHost reports whether a Host header names this machine.
+func isLoopbackHost(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = hostport
+	}
+	host = strings.Trim(host, "[]")
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// cors lets the configured origin call the API from a browser.
+func (s *server) cors(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.allowOrigin != "" && r.Header.Get("Origin") == s.allowOrigin {
+			w.Header().Set("Access-Control-Allow-Origin", s.allowOrigin)
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST")
+			w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+// jobFromPath looks up the job named by the {id} in the request path,
+// writing a 404 if there isn't one.
+func (s *server) jobFromPath(w http.ResponseWriter, r *http.Request) (job, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no job %q", r.PathValue("id")))
+		return job{}, false
+	}
+	j, ok := s.queue.get(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no job %d", id))
+	}
+	return j, ok
+}
+
+func (s *server) handleSubmit(w http.ResponseWriter, r *http.Request) {
+	// Browsers send a form or text/plain from any page without asking
+	// first, but never JSON
+	if typ, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); typ != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, errors.New("send the job as application/json"))
+		return
+	}
+	// Anything the request leaves out comes from the config
+	req := jobRequest{Options: DownloadOptions{Embed: cfg.Embed}}
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	j, err := newJob(req)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, s.queue.add(j))
+}
+
+func (s *server) handleList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.queue.list())
+}
+
+func (s *server) handleJob(w http.ResponseWriter, r *http.Request) {
+	if j, ok := s.jobFromPath(w, r); ok {
+		writeJSON(w, http.StatusOK, j)
+	}
+}
+
+func (s *server) handleCancel(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.jobFromPath(w, r)
+	if !ok {
+		return
+	}
+	if err := s.queue.cancelJob(j.ID); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	j, _ = s.queue.get(j.ID)
+	writeJSON(w, http.StatusOK, j)
+}
+
+func (s *server) handleSummary(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.jobFromPath(w, r)
+	if !ok {
+		return
+	}
+	if j.Summary == "" {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %d has no summary", j.ID))
+		return
+	}
+	if r.URL.Query().Get("format") == "html" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, markdownHTML(j.Summary))
+		return
+	}
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	fmt.Fprintln(w, j.Summary)
+}
+
+// handleFile serves one of a job's output files, by name. Only files the
+// job wrote can be fetched.
+func (s *server) handleFile(w http.ResponseWriter, r *http.Request) {
+	j, ok := s.jobFromPath(w, r)
+	if !ok {
+		return
+	}
+	name := r.PathValue("name")
+	i := slices.IndexFunc(j.Files, func(f string) bool { return filepath.Base(f) == name })
+	if i < 0 {
+		writeError(w, http.StatusNotFound, fmt.Errorf("job %d has no file %q", j.ID, name))
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	http.ServeFile(w, r, j.Files[i])
+}
+
+func (s *server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	url, err := normalizeURL(r.URL.Query().Get("url"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	info, err := fetchInfo(r.Context(), url)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	// Suggest the same output path the menu would
+	writeJSON(w, http.StatusOK, struct {
+		*videoInfo
+		OutPath string `json:"out_path"`
+	}{info, cmp.Or(cfg.OutputDir, ".") + "/" + sanitizeFilename(info.Title)})
+}
+
+func (s *server) handlePresets(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"prompt":  cfg.prompt(),
+		"presets": cfg.presets(),
+		"llm":     llmAvailable,
+	})
+}
+
+//go:embed web
+var webFiles embed.FS
+
+// webUI serves the browser UI: one page, mirroring the menu, that does
+// everything through the API.
+func webUI() http.Handler {
+	files, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		panic(err)
+	}
+	return http.FileServerFS(files)
+}
+
+// handleEvents streams job changes as Server-Sent Events, each a "job"
+// event holding the job as JSON. Every job is sent on connecting, then
+// each one again as it changes.
+func (s *server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("streaming not supported"))
+		return
+	}
+	poke, stop := s.queue.watch()
+	defer stop()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	rev := 0
+	keepalive := time.NewTicker(30 * time.Second)
+	defer keepalive.Stop()
+	for {
+		var jobs []job
+		jobs, rev = s.queue.since(rev)
+		for _, j := range jobs {
+			data, err := json.Marshal(j)
+			if err != nil {
+				return
+			}
+			fmt.Fprintf(w, "event: job\ndata: %s\n\n", data)
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			// A comment, so proxies don't close an idle stream
+			fmt.Fprint(w, ": keepalive\n\n")
+		case <-poke:
+		}
+	}
+}
+
+// serve runs the API on addr until ctx is cancelled, working through the
+// job queue in the background. A non-empty token is required on every
+// request.
+func serve(ctx context.Context, addr, allowOrigin, token string) error {
+	q, err := loadJobQueue()
+	if err != nil {
+		return err
+	}
+	s := &server{queue: q, allowOrigin: allowOrigin, token: token}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	if tcp, ok := ln.Addr().(*net.TCPAddr); ok {
+		s.loopback = tcp.IP.IsLoopback()
+	}
+	if !s.loopback && token == "" {
+		fmt.Fprintln(os.Stderr, "⚠ Listening beyond this machine with no -token: anyone who can reach it can queue downloads")
+	}
+	srv := &http.Server{
+		Handler:           s.routes(),
+		ReadHeaderTimeout: 10 * time.Second,
+		// Requests end with the server, or event streams would hold
+		// up shutting down
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+
+	var wg sync.WaitGroup
+	wg.Go(func() { q.work(ctx) })
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	fmt.Fprintln(os.Stderr, "🌐 tuber is listening on")
+	query := ""
+	if token != "" {
+		query = "?token=" + token
+	}
+	for _, host := range lanHosts(ln.Addr()) {
+		fmt.Fprintf(os.Stderr, "   http://%s/%s\n", host, query)
+	}
+	fmt.Fprintln(os.Stderr, "Press ctrl+c to stop")
+	err = srv.Serve(ln)
+	wg.Wait()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestJobQueuePrune(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-finishedJobsKept - time.Hour)
+	q := &jobQueue{jobs: []*job{
+		{ID: 1, State: jobDone, Finished: old},
+		{ID: 2, State: jobQueued},
+		{ID: 3, State: jobFailed, Finished: now.Add(-time.Hour)},
+		{ID: 4, State: jobCancelled, Finished: old},
+		{ID: 5, State: jobDone, Finished: old},
+	}}
+	q.prune()
+	var ids []int
+	for _, j := range q.jobs {
+		ids = append(ids, j.ID)
+	}
+	// 5 stays as the newest, so IDs carry on from it
+	if want := []int{2, 3, 5}; !slices.Equal(ids, want) {
+		t.Errorf("prune() kept %v, want %v", ids, want)
+	}
+}
+
+func TestConfinePath(t *testing.T) {
+	base := t.TempDir()
+	saved := cfg.OutputDir
+	cfg.OutputDir = base
+	t.Cleanup(func() { cfg.OutputDir = saved })
+
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: "music", want: filepath.Join(base, "music")},
+		{in: "music/../talks/", want: filepath.Join(base, "talks")},
+		{in: ".", want: base},
+		{in: filepath.Join(base, "a", "b"), want: filepath.Join(base, "a", "b")},
+		{in: "..dots", want: filepath.Join(base, "..dots")},
+		{in: "..", wantErr: true},
+		{in: "../elsewhere", wantErr: true},
+		{in: "music/../../elsewhere", wantErr: true},
+		{in: "/etc", wantErr: true},
+		{in: base + "-sibling", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := confinePath(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("confinePath(%q) = %q, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("confinePath(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TranscriberConfig sets up local speech-to-text, used for videos that
+// have no subtitles at all.
+type TranscriberConfig struct {
+	// Command runs the transcriber. It's split on spaces and $VARS are
+	// expanded, then {audio}, {out} and {dir} are replaced with the 16kHz
+	// mono wav to transcribe, a path to write to (without the .vtt) and the
+	// directory both are in. It must leave a .vtt file in {dir}.
+	Command string `json:"command,omitempty"`
+}
+
+var (
+	// whisper.cpp's -pp flag prints "progress = 35%"
+	transcribePercent = regexp.MustCompile(`progress\s*=\s*(\d+)%`)
+	// Most whisper CLIs print each segment as it's done:
+	// "[00:01:02.000 --> 00:01:05.000]  text"
+	transcribeSegment = regexp.MustCompile(`\[[\d:.]+ --> ([\d:.]+)\]`)
+)
+
+// transcribe downloads url's audio and runs the configured transcriber on
+// it, leaving <title>.vtt in dir and returning its path. progress is told
+// what's going on as it goes.
+func transcribe(ctx context.Context, url, dir string, progress func(string)) (string, error) {
+	fields := strings.Fields(cfg.Transcriber.Command)
+	if len(fields) == 0 {
+		return "", errors.New("no transcriber set up")
+	}
+	if _, err := exec.LookPath(os.ExpandEnv(fields[0])); err != nil {
+		return "", fmt.Errorf("transcriber %q not found", fields[0])
+	}
+
+	// Work next to dir so the result can be renamed into place
+	work, err := os.MkdirTemp(dir, ".tuber-transcribe-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(work)
+
+	// Speech-to-text engines want 16kHz mono; whisper.cpp won't take
+	// anything else
+	progress("Downloading audio to transcribe")
+	var out []byte
+	err = withRetry(ctx, func() error {
+		args := []string{
+			"-x",
+			"--audio-format", "wav",
+			"--postprocessor-args", "ExtractAudio:-ar 16000 -ac 1",
+			"-q", "--no-warnings",
+			"--print", "after_move:duration",
+			"--print", "after_move:filepath",
+			"-o", work + "/%(title)s.%(ext)s",
+		}
+		// Not the audio step's args: they could change the format
+		args = append(args, cfg.YtdlpArgs.forStep("")...)
+		out, err = ytdlpOutput(ctx, url, append(args, ytdlpSource(url)...)...)
+		return err
+	}, func(attempt int, delay time.Duration, err error) {
+		progress(fmt.Sprintf("Retrying audio download (attempt %d/%d)", attempt, maxAttempts))
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to download audio to transcribe: %w", err)
+	}
+	lines := outputLines(out)
+	if len(lines) < 2 {
+		return "", errors.New("failed to download audio to transcribe: yt-dlp didn't say where it saved it")
+	}
+	duration, _ := strconv.ParseFloat(lines[0], 64)
+	audio := lines[1]
+	title := strings.TrimSuffix(filepath.Base(audio), filepath.Ext(audio))
+
+	progress("Transcribing audio")
+	vars := strings.NewReplacer("{audio}", audio, "{out}", filepath.Join(work, "transcript"), "{dir}", work)
+	args := make([]string, len(fields))
+	for i, f := range fields {
+		args[i] = vars.Replace(os.ExpandEnv(f))
+	}
+	if err := runTranscriber(ctx, args, duration, progress); err != nil {
+		return "", err
+	}
+
+	entries, err := os.ReadDir(work)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".vtt") {
+			dest := filepath.Join(dir, title+".vtt")
+			if err := os.Rename(filepath.Join(work, entry.Name()), dest); err != nil {
+				return "", err
+			}
+			return dest, nil
+		}
+	}
+	return "", errors.New("the transcriber didn't write a .vtt file (check transcriber.command writes one to {out} or {dir})")
+}
+
+// runTranscriber runs the transcriber command, turning what it prints
+// into progress percentages where it can. duration is the audio's length
+// in seconds, or 0 if unknown.
+func runTranscriber(ctx context.Context, args []string, duration float64, progress func(string)) error {
+	pr, pw := io.Pipe()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	setProcessGroup(cmd)
+	cmd.WaitDelay = 5 * time.Second
+	cmd.Stdout = pw
+	cmd.Stderr = pw
+
+	// Keep the last thing it said in case it fails
+	var last string
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reported := -1
+		scanner := bufio.NewScanner(pr)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			last = line
+
+			pct := -1
+			if m := transcribePercent.FindStringSubmatch(line); m != nil {
+				pct, _ = strconv.Atoi(m[1])
+			} else if m := transcribeSegment.FindStringSubmatch(line); m != nil && duration > 0 {
+				if end, ok := parseVTTTime(m[1]); ok {
+					pct = int(min(end/duration, 1) * 100)
+				}
+			}
+			// Tens are plenty, and keep the log readable
+			if pct >= 0 && pct/10 != reported/10 {
+				reported = pct
+				progress(fmt.Sprintf("Transcribing audio (%d%%)", pct/10*10))
+			}
+		}
+		io.Copy(io.Discard, pr)
+	}()
+
+	err := cmd.Run()
+	pw.Close()
+	<-done
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if last != "" {
+			return fmt.Errorf("transcriber failed: %s", last)
+		}
+		return fmt.Errorf("transcriber failed: %w", err)
+	}
+	return nil
+}
+
+// logProgress returns a progress func that writes each new status to log
+// on its own line.
+func logProgress(log io.Writer) func(string) {
+	var last string
+	return func(status string) {
+		if status != last {
+			last = status
+			fmt.Fprintf(log, "🎙 %s...\n", status)
+		}
+	}
+}
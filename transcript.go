@@ -0,0 +1,184 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A cue is one line of a transcript and when it was said.
+type cue struct {
+	Start float64 `json:"start"` // seconds
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// parseVTT turns a WebVTT file into cues, one per distinct line of text.
+// YouTube's auto-captions repeat each line in the following cue as it
+// scrolls up, so repeated lines are dropped the same way extractText
+// drops them.
+func parseVTT(content string) []cue {
+	var cues []cue
+	seen := make(map[string]bool)
+
+	var start, end float64
+	inCue := false
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if strings.Contains(line, "-->") {
+			s, e, ok := parseCueTiming(line)
+			if !ok {
+				inCue = false
+				continue
+			}
+			start, end, inCue = s, e, true
+			continue
+		}
+		// Only a truly empty line ends a cue; auto-captions have lines
+		// that are just a space inside cues
+		if line == "" {
+			inCue = false
+			continue
+		}
+		if !inCue {
+			// Header, NOTE blocks, cue identifiers
+			continue
+		}
+
+		text := strings.TrimSpace(stripTags(line))
+		if text == "" || seen[text] {
+			continue
+		}
+		seen[text] = true
+		cues = append(cues, cue{Start: start, End: end, Text: text})
+	}
+	return cues
+}
+
+// parseCueTiming parses "00:01:02.345 --> 00:01:04.000 align:start ...".
+func parseCueTiming(line string) (start, end float64, ok bool) {
+	from, rest, found := strings.Cut(line, "-->")
+	if !found {
+		return 0, 0, false
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return 0, 0, false
+	}
+	start, ok1 := parseVTTTime(strings.TrimSpace(from))
+	end, ok2 := parseVTTTime(fields[0])
+	return start, end, ok1 && ok2
+}
+
+// parseVTTTime parses hh:mm:ss.ttt or mm:ss.ttt into seconds.
+func parseVTTTime(s string) (float64, bool) {
+	s = strings.Replace(s, ",", ".", 1)
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	var total float64
+	for _, p := range parts {
+		v, err := strconv.ParseFloat(p, 64)
+		if err != nil {
+			return 0, false
+		}
+		total = total*60 + v
+	}
+	return total, true
+}
+
+// timestampedTranscript renders cues one per line as "[mm:ss] text", the
+// form the LLM sees when it needs to cite times.
+func timestampedTranscript(cues []cue) string {
+	var b strings.Builder
+	for _, c := range cues {
+		fmt.Fprintf(&b, "[%s] %s\n", formatDuration(c.Start), c.Text)
+	}
+	return b.String()
+}
+
+// timestampRef matches a [mm:ss] or [h:mm:ss] citation.
+var timestampRef = regexp.MustCompile(`\[\d+:\d\d(:\d\d)?\]`)
+
+// timestampInstructions are added to the summary prompt so the summary
+// points back into the video.
+const timestampInstructions = "\n\nEach transcript line starts with the [mm:ss] time it's said. " +
+	"Write the summary as bullet points and end each bullet with the [mm:ss] time of the " +
+	"transcript line it's based on, copied exactly as it appears in the transcript."
+
+// linkTimestamps turns each [mm:ss] citation in text into a Markdown link
+// to that moment in the video. Citations that don't match the start of a
+// line of the transcript are left as plain text and returned, so made-up
+// times never get a link. A citation matches however it's padded, so
+// [00:12] and [0:00:12] both mean [0:12]. Without a videoID nothing is
+// linked, but citations are still checked.
+func linkTimestamps(text string, cues []cue, videoID string) (linked string, invalid []string) {
+	starts := make(map[int]bool)
+	for _, c := range cues {
+		starts[int(c.Start)] = true
+	}
+
+	linked = timestampRef.ReplaceAllStringFunc(text, func(ts string) string {
+		sec, ok := citedSecond(ts)
+		if !ok || !starts[sec] {
+			invalid = append(invalid, ts)
+			return ts
+		}
+		if videoID == "" {
+			return ts
+		}
+		return fmt.Sprintf("%s(https://youtu.be/%s?t=%d)", ts, videoID, sec)
+	})
+	return linked, invalid
+}
+
+// citedSecond parses a [mm:ss] or [h:mm:ss] citation into seconds.
+// Minutes and seconds past 59 aren't a real time, so they don't parse.
+func citedSecond(ts string) (int, bool) {
+	parts := strings.Split(strings.Trim(ts, "[]"), ":")
+	sec := 0
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil || (i > 0 && v > 59) {
+			return 0, false
+		}
+		sec = sec*60 + v
+	}
+	return sec, true
+}
+
+// fetchCues downloads a video's subtitles and parses them into cues. The
+// title comes from the subtitle filename, saving a metadata fetch. yt-dlp's
+// progress goes to log.
+func fetchCues(ctx context.Context, url string, log io.Writer) (title string, cues []cue, err error) {
+	tmpDir, err := os.MkdirTemp("", "tuber-cues-")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	vttPath, err := downloadVTT(ctx, url, tmpDir, log)
+	if err != nil {
+		return "", nil, err
+	}
+	content, err := os.ReadFile(vttPath)
+	if err != nil {
+		return "", nil, err
+	}
+	cues = parseVTT(string(content))
+	if len(cues) == 0 {
+		return "", nil, fmt.Errorf("the subtitles for this video are empty")
+	}
+
+	// <title>.<lang>.vtt
+	title = strings.TrimSuffix(filepath.Base(vttPath), ".vtt")
+	title = strings.TrimSuffix(title, filepath.Ext(title))
+	return title, cues, nil
+}
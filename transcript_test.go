@@ -0,0 +1,108 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+const testVTT = `WEBVTT
+Kind: captions
+Language: en
+
+00:00:00.000 --> 00:00:02.500 align:start position:0%
+hello <c>and</c> welcome
+
+
+00:00:02.500 --> 00:00:05.000 align:start position:0%
+hello and welcome
+to the show
+
+NOTE this isn't a cue
+
+intro
+00:01:05,250 --> 00:01:07.000
+<00:01:05.500><c>second</c> part
+
+bad --> timing
+ignored text
+`
+
+func TestParseVTT(t *testing.T) {
+	want := []cue{
+		{Start: 0, End: 2.5, Text: "hello and welcome"},
+		{Start: 2.5, End: 5, Text: "to the show"},
+		{Start: 65.25, End: 67, Text: "second part"},
+	}
+	if got := parseVTT(testVTT); !slices.Equal(got, want) {
+		t.Errorf("parseVTT() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseVTTTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"00:00:01.500", 1.5, true},
+		{"01:02:03.000", 3723, true},
+		{"02:03.250", 123.25, true},
+		{"00:00:01,500", 1.5, true},
+		{"5", 0, false},
+		{"1:2:3:4", 0, false},
+		{"aa:bb", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseVTTTime(tt.in)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("parseVTTTime(%q) = %v, %v, want %v, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestLinkTimestamps(t *testing.T) {
+	cues := []cue{{Start: 12.4, Text: "a"}, {Start: 65, Text: "b"}, {Start: 3725.9, Text: "c"}}
+	tests := []struct {
+		name, text, videoID, want string
+		invalid                   []string
+	}{
+		{
+			name:    "exact",
+			text:    "- intro [0:12]\n- more [1:05]\n- end [1:02:05]",
+			videoID: "dQw4w9WgXcQ",
+			want: "- intro [0:12](https://youtu.be/dQw4w9WgXcQ?t=12)\n- more [1:05](https://youtu.be/dQw4w9WgXcQ?t=65)\n" +
+				"- end [1:02:05](https://youtu.be/dQw4w9WgXcQ?t=3725)",
+		},
+		{
+			name:    "padded",
+			text:    "[00:12] [0:01:05] [01:02:05] [62:05]",
+			videoID: "dQw4w9WgXcQ",
+			want: "[00:12](https://youtu.be/dQw4w9WgXcQ?t=12) [0:01:05](https://youtu.be/dQw4w9WgXcQ?t=65) " +
+				"[01:02:05](https://youtu.be/dQw4w9WgXcQ?t=3725) [62:05](https://youtu.be/dQw4w9WgXcQ?t=3725)",
+		},
+		{
+			name:    "invented",
+			text:    "- real [0:12]\n- made up [0:30]\n- not a time [0:72]",
+			videoID: "dQw4w9WgXcQ",
+			want:    "- real [0:12](https://youtu.be/dQw4w9WgXcQ?t=12)\n- made up [0:30]\n- not a time [0:72]",
+			invalid: []string{"[0:30]", "[0:72]"},
+		},
+		{
+			name:    "no video ID",
+			text:    "[0:12] [0:13]",
+			want:    "[0:12] [0:13]",
+			invalid: []string{"[0:13]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, invalid := linkTimestamps(tt.text, cues, tt.videoID)
+			if got != tt.want {
+				t.Errorf("linkTimestamps() = %q, want %q", got, tt.want)
+			}
+			if !slices.Equal(invalid, tt.invalid) {
+				t.Errorf("linkTimestamps() invalid = %q, want %q", invalid, tt.invalid)
+			}
+		})
+	}
+}
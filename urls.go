@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var videoID = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// youtubeParams are the query parameters worth keeping on YouTube URLs;
+// everything else (si, feature, pp, ...) is tracking or UI state.
+var youtubeParams = map[string]bool{"v": true, "list": true, "index": true, "t": true}
+
+// trackingParams are stripped from URLs on other sites.
+var trackingParams = map[string]bool{
+	"si": true, "fbclid": true, "gclid": true, "igshid": true, "mc_cid": true, "mc_eid": true,
+	"ref_src": true, "feature": true, "share_source": true,
+}
+
+// normalizeURL checks raw looks like something yt-dlp can fetch and puts it
+// in a canonical form: YouTube short, shorts, live, embed and mobile links
+// become www.youtube.com/watch?v=ID, and tracking parameters are dropped.
+func normalizeURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", errors.New("enter a URL")
+	}
+	if strings.ContainsAny(raw, " \t\n") {
+		return "", errors.New("URL can't contain spaces")
+	}
+	if !strings.Contains(raw, "://") {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", errors.New("that doesn't look like a URL")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", errors.New("URL must start with http:// or https://")
+	}
+	host := strings.ToLower(u.Hostname())
+	if !strings.Contains(host, ".") {
+		return "", errors.New("that doesn't look like a URL")
+	}
+
+	if isYouTubeHost(host) {
+		return normalizeYouTube(host, u)
+	}
+
+	q := u.Query()
+	for k := range q {
+		if strings.HasPrefix(k, "utm_") || trackingParams[k] {
+			q.Del(k)
+		}
+	}
+	u.RawQuery = q.Encode()
+	u.Fragment = ""
+	return u.String(), nil
+}
+
+func isYouTubeHost(host string) bool {
+	host = strings.TrimPrefix(host, "www.")
+	switch host {
+	case "youtube.com", "m.youtube.com", "music.youtube.com", "youtu.be", "youtube-nocookie.com":
+		return true
+	}
+	return false
+}
+
+func normalizeYouTube(host string, u *url.URL) (string, error) {
+	q := u.Query()
+	path := strings.TrimSuffix(u.Path, "/")
+
+	// Pull the video ID out of the various link shapes
+	id := ""
+	switch {
+	case strings.TrimPrefix(host, "www.") == "youtu.be":
+		id = strings.TrimPrefix(path, "/")
+	case path == "/watch":
+		id = q.Get("v")
+	default:
+		for _, prefix := range []string{"/shorts/", "/live/", "/embed/", "/v/"} {
+			if strings.HasPrefix(path, prefix) {
+				id = strings.TrimPrefix(path, prefix)
+			}
+		}
+	}
+
+	out := url.URL{Scheme: "https", Host: "www.youtube.com"}
+	if host == "music.youtube.com" {
+		out.Host = host
+	}
+
+	kept := url.Values{}
+	for k, v := range q {
+		if youtubeParams[k] {
+			kept[k] = v
+		}
+	}
+
+	switch {
+	case id != "":
+		if !videoID.MatchString(id) {
+			return "", errors.New("that YouTube link doesn't have a valid video ID")
+		}
+		// Keep v first so the URL reads like the ones people copy
+		kept.Del("v")
+		out.Path = "/watch"
+		out.RawQuery = "v=" + id
+		if len(kept) > 0 {
+			out.RawQuery += "&" + kept.Encode()
+		}
+		return out.String(), nil
+	case path == "/watch":
+		return "", errors.New("that YouTube link is missing its video ID (?v=...)")
+	case path == "" || path == "/results" || path == "/feed/subscriptions":
+		return "", errors.New("that's not a video, channel or playlist link")
+	default:
+		// Channels (@handle, /channel/, /c/), playlists and the like
+		out.Path = path
+		if path == "/playlist" && kept.Get("list") == "" {
+			return "", errors.New("that playlist link is missing its list ID")
+		}
+	}
+
+	out.RawQuery = kept.Encode()
+	return out.String(), nil
+}
+
+// youtubeID returns the video ID from a YouTube watch URL, or "" for
+// anything else.
+func youtubeID(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || !isYouTubeHost(strings.ToLower(u.Hostname())) || u.Path != "/watch" {
+		return ""
+	}
+	if id := u.Query().Get("v"); videoID.MatchString(id) {
+		return id
+	}
+	return ""
+}
@@ -0,0 +1,64 @@
+package main
+
+import "testing"
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "https://www.youtube.com/watch?v=dQw4w9WgXcQ", want: "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{in: "  youtube.com/watch?v=dQw4w9WgXcQ  ", want: "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{in: "https://youtu.be/dQw4w9WgXcQ?si=abc123", want: "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{in: "https://youtu.be/dQw4w9WgXcQ?t=42", want: "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42"},
+		{in: "https://m.youtube.com/watch?v=dQw4w9WgXcQ&feature=share", want: "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{in: "https://www.youtube.com/shorts/dQw4w9WgXcQ", want: "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{in: "https://www.youtube.com/live/dQw4w9WgXcQ?pp=x", want: "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{in: "https://www.youtube-nocookie.com/embed/dQw4w9WgXcQ", want: "https://www.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{in: "https://www.youtube.com/watch?list=PL123&v=dQw4w9WgXcQ&index=2", want: "https://www.youtube.com/watch?v=dQw4w9WgXcQ&index=2&list=PL123"},
+		{in: "https://music.youtube.com/watch?v=dQw4w9WgXcQ", want: "https://music.youtube.com/watch?v=dQw4w9WgXcQ"},
+		{in: "https://www.youtube.com/@channel/", want: "https://www.youtube.com/@channel"},
+		{in: "https://www.youtube.com/playlist?list=PL123&si=x", want: "https://www.youtube.com/playlist?list=PL123"},
+		{in: "https://vimeo.com/123?utm_source=x&fbclid=y&h=z#t=5", want: "https://vimeo.com/123?h=z"},
+		{in: "", wantErr: true},
+		{in: "https://youtube.com/watch?v=dQw4w9 WgXcQ", wantErr: true},
+		{in: "ftp://example.com/video", wantErr: true},
+		{in: "localhost/video", wantErr: true},
+		{in: "https://youtu.be/abc", wantErr: true},
+		{in: "https://www.youtube.com/watch", wantErr: true},
+		{in: "https://www.youtube.com/", wantErr: true},
+		{in: "https://www.youtube.com/playlist", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := normalizeURL(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("normalizeURL(%q) = %q, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("normalizeURL(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestYoutubeID(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ", "dQw4w9WgXcQ"},
+		{"https://music.youtube.com/watch?v=dQw4w9WgXcQ&list=PL1", "dQw4w9WgXcQ"},
+		{"https://youtu.be/dQw4w9WgXcQ", ""},
+		{"https://www.youtube.com/watch?v=short", ""},
+		{"https://www.youtube.com/@channel", ""},
+		{"https://vimeo.com/watch?v=dQw4w9WgXcQ", ""},
+		{"/tmp/video.info.json", ""},
+	}
+	for _, tt := range tests {
+		if got := youtubeID(tt.in); got != tt.want {
+			t.Errorf("youtubeID(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A subscription is a channel or playlist that tuber watch checks for new
+// uploads, and what to do with them.
+type subscription struct {
+	Name      string          `json:"name,omitempty"`
+	URL       string          `json:"url"`
+	Options   DownloadOptions `json:"options"`
+	OutputDir string          `json:"output_dir,omitempty"`
+	Template  string          `json:"template,omitempty"` // yt-dlp filename template, e.g. "%(upload_date)s %(title)s"
+	Backfill  int             `json:"backfill,omitempty"` // uploads from before subscribing to get on the first check
+}
+
+// subscriptionList is the subscriptions file.
+type subscriptionList struct {
+	Interval      string         `json:"interval,omitempty"` // between checks, e.g. "30m"; default 1h
+	Subscriptions []subscription `json:"subscriptions"`
+}
+
+const (
+	defaultWatchInterval = time.Hour
+	// watchWindow is how many of the newest uploads each check looks at
+	watchWindow = 15
+)
+
+func (s subscription) label() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.URL
+}
+
+func subscriptionsPath() (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "subscriptions.json"), nil
+}
+
+// loadSubscriptions reads the subscriptions file. A missing file is an
+// empty list.
+func loadSubscriptions() (subscriptionList, error) {
+	var l subscriptionList
+	path, err := subscriptionsPath()
+	if err != nil {
+		return l, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return l, err
+	}
+	if err := json.Unmarshal(data, &l); err != nil {
+		return l, fmt.Errorf("%s: %w", path, err)
+	}
+	if _, err := l.interval(); err != nil {
+		return l, fmt.Errorf("%s: %w", path, err)
+	}
+	return l, nil
+}
+
+func saveSubscriptions(l subscriptionList) error {
+	path, err := subscriptionsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+func (l subscriptionList) interval() (time.Duration, error) {
+	if l.Interval == "" {
+		return defaultWatchInterval, nil
+	}
+	d, err := time.ParseDuration(l.Interval)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("interval %q should be a duration like 30m or 2h", l.Interval)
+	}
+	return d, nil
+}
+
+// find returns the index of the subscription with the given name or URL,
+// or -1.
+func (l subscriptionList) find(nameOrURL string) int {
+	normalized, _ := normalizeURL(nameOrURL)
+	return slices.IndexFunc(l.Subscriptions, func(s subscription) bool {
+		return s.Name == nameOrURL || s.URL == nameOrURL || s.URL == normalized
+	})
+}
+
+// channelRoot matches a channel's home page, whose flat listing is its
+// tabs rather than its videos.
+var channelRoot = regexp.MustCompile(`^/(@[^/]+|channel/[^/]+|c/[^/]+|user/[^/]+)/?$`)
+
+// uploadsURL points channel links at the channel's videos tab.
+func uploadsURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || !isYouTubeHost(strings.ToLower(u.Hostname())) || !channelRoot.MatchString(u.Path) {
+		return rawURL
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/videos"
+	return u.String()
+}
+
+// An upload is one entry in a channel or playlist.
+type upload struct {
+	Key      string // download archive key: "<extractor> <id>"
+	URL      string
+	Title    string
+	Channel  string
+	Duration float64 // seconds, 0 if unknown
+}
+
+// listUploads returns the newest n uploads in a channel or playlist, or
+// all of them if n is 0, newest first, without fetching each video's
+// details.
+func listUploads(ctx context.Context, rawURL string, n int) ([]upload, error) {
+	args := []string{"--flat-playlist"}
+	if n > 0 {
+		args = append(args, "--playlist-end", fmt.Sprint(n))
+	}
+	args = append(args,
+		"--no-warnings",
+		"--print", "%(ie_key)s\t%(id)s\t%(url)s\t%(duration)s\t%(channel,playlist_uploader)s\t%(title)s",
+	)
+	args = append(args, cfg.YtdlpArgs.forStep("info")...)
+	args = append(args, uploadsURL(rawURL))
+	var out []byte
+	err := withRetry(ctx, func() error {
+		var err error
+		out, err = ytdlpOutput(ctx, rawURL, args...)
+		return err
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var uploads []upload
+	for _, line := range outputLines(out) {
+		fields := strings.SplitN(line, "\t", 6)
+		if len(fields) != 6 || fields[1] == "NA" {
+			continue
+		}
+		extractor := strings.ToLower(fields[0])
+		if extractor == "na" {
+			extractor = "youtube"
+		}
+		u := upload{Key: extractor + " " + fields[1], URL: fields[2], Title: fields[5]}
+		if u.URL == "NA" {
+			u.URL = "https://www.youtube.com/watch?v=" + fields[1]
+		}
+		u.Duration, _ = strconv.ParseFloat(fields[3], 64)
+		if fields[4] != "NA" {
+			u.Channel = fields[4]
+		}
+		uploads = append(uploads, u)
+	}
+	return uploads, nil
+}
+
+var nonAlnum = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// archivePath returns where a subscription's download archive lives. It's
+// in yt-dlp's --download-archive format, one "<extractor> <id>" per line.
+func archivePath(s subscription) (string, error) {
+	dir, err := configDir()
+	if err != nil {
+		return "", err
+	}
+	name := strings.TrimPrefix(strings.TrimPrefix(s.URL, "https://"), "www.")
+	name = strings.Trim(nonAlnum.ReplaceAllString(name, "-"), "-")
+	return filepath.Join(dir, "archive", name+".txt"), nil
+}
+
+// loadArchive returns the keys in an archive file, and whether the file
+// exists at all, which it doesn't until a subscription's first check.
+func loadArchive(path string) (map[string]bool, bool, error) {
+	seen := make(map[string]bool)
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return seen, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			seen[line] = true
+		}
+	}
+	return seen, true, scanner.Err()
+}
+
+// appendArchive records keys as done, creating the archive if need be.
+func appendArchive(path string, keys ...string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	for _, key := range keys {
+		if _, err := fmt.Fprintln(f, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSubscription downloads whatever s has uploaded since the last
+// check, oldest first. On the first check, everything already there
+// except the newest Backfill uploads is marked as seen, so subscribing
+// doesn't download a whole channel.
+func checkSubscription(ctx context.Context, s subscription) error {
+	if (s.Options.Summary || s.Options.Chapters) && !llmAvailable {
+		return requireLLM()
+	}
+	archive, err := archivePath(s)
+	if err != nil {
+		return err
+	}
+	seen, exists, err := loadArchive(archive)
+	if err != nil {
+		return err
+	}
+	uploads, err := listUploads(ctx, s.URL, max(watchWindow, s.Backfill))
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		keep := min(s.Backfill, len(uploads))
+		var old []string
+		for _, u := range uploads[keep:] {
+			old = append(old, u.Key)
+		}
+		if err := appendArchive(archive, old...); err != nil {
+			return err
+		}
+		uploads = uploads[:keep]
+		fmt.Fprintf(os.Stderr, "📺 %s: first check, %d earlier uploads marked as seen\n", s.label(), len(old))
+	}
+
+	var fresh []upload
+	for _, u := range slices.Backward(uploads) {
+		if !seen[u.Key] {
+			fresh = append(fresh, u)
+		}
+	}
+	if len(fresh) == 0 {
+		fmt.Fprintf(os.Stderr, "📺 %s: nothing new\n", s.label())
+		return nil
+	}
+	fmt.Fprintf(os.Stderr, "📺 %s: %d new\n", s.label(), len(fresh))
+	if exists && len(fresh) == len(uploads) && len(uploads) >= watchWindow {
+		// Nothing in the window was seen before, so there may be more
+		// new uploads further back
+		fmt.Fprintf(os.Stderr, "⚠ %s: all of the newest %d uploads are new, so older ones may have been missed; "+
+			"check more often, or set a larger backfill to look further back\n", s.label(), len(uploads))
+	}
+
+	// The download settings are globals, so set them up for this
+	// subscription
+	outputDir = s.OutputDir
+	if outputDir == "" {
+		outputDir = cfg.OutputDir
+	}
+	customOutPath = ""
+	if s.Template != "" {
+		dir := outputDir
+		if dir == "" {
+			dir = "."
+		}
+		customOutPath = dir + "/" + s.Template
+	}
+
+	for i, u := range fresh {
+		if i > 0 {
+			if err := pause(ctx, u.URL); err != nil {
+				return err
+			}
+		}
+		err := runAndReport(ctx, u.URL, &videoInfo{Title: u.Title, Channel: u.Channel, Duration: u.Duration}, s.Options)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %s: %v\n", u.Title, err)
+			var authErr *authError
+			if isRetryable(err) || errors.As(err, &authErr) {
+				// Try again next time, by when the failure may have
+				// passed or the sign-in been fixed
+				continue
+			}
+		}
+		if err := appendArchive(archive, u.Key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watch checks every subscription, then again every interval until ctx
+// is cancelled. The subscriptions file is re-read each time, so edits
+// apply without restarting.
+func watch(ctx context.Context, interval time.Duration, once bool) error {
+	for {
+		list, err := loadSubscriptions()
+		if err != nil {
+			return err
+		}
+		if len(list.Subscriptions) == 0 {
+			return errors.New("no subscriptions yet: add one with tuber watch add <url>")
+		}
+		wait := interval
+		if wait == 0 {
+			wait, _ = list.interval()
+		}
+
+		for _, s := range list.Subscriptions {
+			if err := checkSubscription(ctx, s); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				fmt.Fprintf(os.Stderr, "✗ %s: %v\n", s.label(), err)
+			}
+		}
+		if once {
+			return nil
+		}
+
+		fmt.Fprintf(os.Stderr, "\n💤 Next check at %s\n", time.Now().Add(wait).Format("15:04"))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
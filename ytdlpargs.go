@@ -0,0 +1,148 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// YtdlpArgsConfig holds extra arguments for yt-dlp, for the options tuber
+// doesn't have flags for. They go after tuber's own, so they win.
+type YtdlpArgsConfig struct {
+	All   []string `json:"all,omitempty"`   // every yt-dlp run
+	Video []string `json:"video,omitempty"` // downloading video
+	Audio []string `json:"audio,omitempty"` // downloading audio
+	Subs  []string `json:"subs,omitempty"`  // downloading subtitles, for transcripts and summaries
+	Info  []string `json:"info,omitempty"`  // fetching metadata and listing channels
+}
+
+// ytdlpSteps are the sections extra args can be given for after --.
+var ytdlpSteps = []string{"all", "video", "audio", "subs", "info"}
+
+func (y *YtdlpArgsConfig) step(name string) *[]string {
+	switch name {
+	case "all":
+		return &y.All
+	case "video":
+		return &y.Video
+	case "audio":
+		return &y.Audio
+	case "subs":
+		return &y.Subs
+	case "info":
+		return &y.Info
+	}
+	return nil
+}
+
+// forStep returns the extra args for a step: the ones for every run,
+// then the step's own. An empty step gets just the ones for every run.
+func (y YtdlpArgsConfig) forStep(step string) []string {
+	if step == "" {
+		return y.All
+	}
+	return slices.Concat(y.All, *y.step(step))
+}
+
+// deniedYtdlpArgs would break how tuber runs yt-dlp: where files go,
+// what it prints to stdout, and whether it downloads at all. Aliases and
+// options that list something and exit count too, as do ways of sneaking
+// any of them in.
+var deniedYtdlpArgs = []string{
+	// Where files go
+	"-o", "--output", "-P", "--paths", "--load-info-json", "-a", "--batch-file", "--download-archive",
+	// What's printed
+	"-q", "--quiet", "--no-quiet", "--progress", "--newline", "-O", "--print", "--print-to-file",
+	"-j", "--dump-json", "-J", "--dump-single-json", "--print-json", "--dump-pages", "--dump-intermediate-pages",
+	"-g", "--get-url", "-e", "--get-title", "--get-id", "--get-thumbnail", "--get-description",
+	"--get-duration", "--get-filename", "--get-format",
+	// Whether anything's downloaded
+	"-s", "--simulate", "--no-simulate", "--skip-download", "--no-download", "--flat-playlist",
+	"-F", "--list-formats", "--list-subs", "--list-thumbnails", "--list-extractors", "--extractor-descriptions",
+	"--list-impersonate-targets", "--dump-user-agent", "-U", "--update", "--update-to", "--version", "-h", "--help",
+	// Ways to pass any of the above
+	"--alias", "--config-location", "--config-locations",
+}
+
+// check reports any args tuber can't allow.
+func (y YtdlpArgsConfig) check() error {
+	for _, name := range ytdlpSteps {
+		for _, arg := range *y.step(name) {
+			if deniedYtdlpArg(arg) {
+				return fmt.Errorf("yt-dlp arg %s can't be used, tuber needs to control it", arg)
+			}
+		}
+	}
+	return nil
+}
+
+// ytdlpValueFlags are yt-dlp's short options that take a value, which in
+// a bundle like -xfbest is the rest of the arg.
+const ytdlpValueFlags = "aoOPfSrRNupI2t"
+
+// deniedYtdlpArg reports whether arg is one of deniedYtdlpArgs, however
+// it's written: --opt=value, -oVALUE, a long option cut short, which
+// yt-dlp accepts as long as it's unambiguous, or short options bundled
+// together like -wo. A bare -- is denied too, since it would turn the
+// source tuber adds after the extra args into a filename.
+func deniedYtdlpArg(arg string) bool {
+	if name, ok := strings.CutPrefix(arg, "--"); ok {
+		name, _, _ = strings.Cut(name, "=")
+		if name == "" {
+			return true
+		}
+		for _, denied := range deniedYtdlpArgs {
+			if long, ok := strings.CutPrefix(denied, "--"); ok && strings.HasPrefix(long, name) {
+				return true
+			}
+		}
+		return false
+	}
+	if !strings.HasPrefix(arg, "-") {
+		return false
+	}
+	for _, c := range arg[1:] {
+		if slices.Contains(deniedYtdlpArgs, "-"+string(c)) {
+			return true
+		}
+		if strings.ContainsRune(ytdlpValueFlags, c) {
+			// The rest is its value
+			return false
+		}
+	}
+	return false
+}
+
+// parseYtdlpArgs reads the yt-dlp args given after -- on the command
+// line. They're for every run, unless a "video:", "audio:", "subs:" or
+// "info:" marks the ones that follow as just for that step.
+func parseYtdlpArgs(args []string) YtdlpArgsConfig {
+	var y YtdlpArgsConfig
+	target := &y.All
+	for _, arg := range args {
+		if name, ok := strings.CutSuffix(arg, ":"); ok && slices.Contains(ytdlpSteps, name) {
+			target = y.step(name)
+			continue
+		}
+		*target = append(*target, arg)
+	}
+	return y
+}
+
+// parseRunFlags parses the flags of a command that runs yt-dlp. Anything
+// after -- is extra yt-dlp args, added to the configured ones.
+func parseRunFlags(fs *flag.FlagSet, args []string) error {
+	var extra []string
+	if i := slices.Index(args, "--"); i >= 0 {
+		args, extra = args[:i], args[i+1:]
+	}
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	given := parseYtdlpArgs(extra)
+	for _, name := range ytdlpSteps {
+		*cfg.YtdlpArgs.step(name) = append(*cfg.YtdlpArgs.step(name), *given.step(name)...)
+	}
+	return cfg.YtdlpArgs.check()
+}
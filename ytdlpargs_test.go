@@ -0,0 +1,112 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeniedYtdlpArg(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want bool
+	}{
+		// Allowed
+		{"--sub-langs=en,de", false},
+		{"--no-progress", false},
+		{"--cookies-from-browser", false},
+		{"--referer", false},
+		{"-fbest", false},
+		{"-f", false},
+		{"-x", false},
+		{"-xk", false},
+		{"-fbestvideo-o", false},
+		{"-S+res", false},
+		{"-N4", false},
+		{"-4", false},
+		{"best", false},
+		{"/tmp/out", false},
+		{"-", false},
+
+		// Denied as given
+		{"-o", true},
+		{"--output", true},
+		{"--print-json", true},
+		{"--no-download", true},
+		{"--alias", true},
+		{"--config-locations", true},
+
+		// With their value attached
+		{"-o/tmp/x", true},
+		{"--output=/tmp/x", true},
+		{"-P/tmp", true},
+		{"--print=%(title)s", true},
+
+		// Cut short
+		{"--dump-j", true},
+		{"--print-to", true},
+		{"--out", true},
+		{"--simul", true},
+		{"--no-dow", true},
+
+		// Bundled
+		{"-wo/tmp/x", true},
+		{"-is", true},
+		{"-xq", true},
+		{"-kjx", true},
+		{"-wO%(title)s", true},
+		{"-iF", true},
+
+		// A bare --
+		{"--", true},
+		{"--=x", true},
+	}
+	for _, tt := range tests {
+		if got := deniedYtdlpArg(tt.arg); got != tt.want {
+			t.Errorf("deniedYtdlpArg(%q) = %v, want %v", tt.arg, got, tt.want)
+		}
+	}
+}
+
+func TestParseYtdlpArgs(t *testing.T) {
+	got := parseYtdlpArgs([]string{
+		"--embed-metadata",
+		"video:", "-S", "res:720",
+		"audio:", "--audio-quality", "5",
+		"all:", "--no-mtime",
+		"subs:", "--sub-langs", "en,de",
+		"info:", "--extractor-args", "youtube:player_client=web",
+		"video:", "--no-part",
+		"nope:",
+	})
+	want := YtdlpArgsConfig{
+		All:   []string{"--embed-metadata", "--no-mtime"},
+		Video: []string{"-S", "res:720", "--no-part", "nope:"},
+		Audio: []string{"--audio-quality", "5"},
+		Subs:  []string{"--sub-langs", "en,de"},
+		Info:  []string{"--extractor-args", "youtube:player_client=web"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseYtdlpArgs() = %+v, want %+v", got, want)
+	}
+	if args := want.forStep("audio"); !reflect.DeepEqual(args, []string{"--embed-metadata", "--no-mtime", "--audio-quality", "5"}) {
+		t.Errorf("forStep(audio) = %q", args)
+	}
+	if args := want.forStep(""); !reflect.DeepEqual(args, want.All) {
+		t.Errorf("forStep(\"\") = %q", args)
+	}
+}
+
+func TestYtdlpArgsCheck(t *testing.T) {
+	if err := (YtdlpArgsConfig{All: []string{"--no-mtime"}, Video: []string{"-fbest"}}).check(); err != nil {
+		t.Errorf("check() = %v, want nil", err)
+	}
+	for _, y := range []YtdlpArgsConfig{
+		{All: []string{"--no-mtime", "--"}},
+		{Audio: []string{"-wo/tmp/x"}},
+		{Info: []string{"--dump-j"}},
+	} {
+		if err := y.check(); err == nil {
+			t.Errorf("%+v: check() = nil, want an error", y)
+		}
+	}
+}